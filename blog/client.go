@@ -7,6 +7,12 @@ import (
 )
 
 type BlogClient interface {
-	GetPostsFetcher(ctx context.Context) lib.ChunkIteratorFunc[string]
+	GetPostsFetcher(ctx context.Context) lib.ChunkIteratorFunc[Post]
 	CreatePost(ctx context.Context, body string) error
 }
+
+// AccountNamer is implemented by clients which can tell the account posts are fetched from.
+type AccountNamer interface {
+	// Returns the name of the account, e.g. "@user@host" on Mastodon.
+	AccountName(ctx context.Context) (string, error)
+}
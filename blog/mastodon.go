@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/paralleltree/markov-bot-go/lib"
 )
@@ -39,10 +40,10 @@ func NewMastodonClient(origin, accessToken string, postVisibility string) BlogCl
 	}
 }
 
-func (c *MastodonClient) GetPostsFetcher(ctx context.Context) lib.ChunkIteratorFunc[string] {
+func (c *MastodonClient) GetPostsFetcher(ctx context.Context) lib.ChunkIteratorFunc[Post] {
 	userId := ""
 	maxId := ""
-	return func() ([]string, bool, error) {
+	return func() ([]Post, bool, error) {
 		if userId == "" {
 			gotUserId, err := c.FetchUserId(ctx)
 			if err != nil {
@@ -52,9 +53,9 @@ func (c *MastodonClient) GetPostsFetcher(ctx context.Context) lib.ChunkIteratorF
 		}
 
 		chunkSize := 100
-		statuses, hasNext, nextMaxId, err := c.fetchPublicStatusesChunk(ctx, userId, chunkSize, maxId)
+		statuses, hasNext, nextMaxId, err := c.fetchStatusesChunk(ctx, userId, chunkSize, maxId)
 		if err != nil {
-			return nil, false, fmt.Errorf("fetch public statuses: %w", err)
+			return nil, false, fmt.Errorf("fetch statuses: %w", err)
 		}
 		maxId = nextMaxId
 		return statuses, hasNext, nil
@@ -62,8 +63,8 @@ func (c *MastodonClient) GetPostsFetcher(ctx context.Context) lib.ChunkIteratorF
 }
 
 // Returns status slice and minimum status id to fetch next older statuses.
-// This function may returns statuses lesser than specified count because this exlcludes private and direct visibility statuses.
-func (c *MastodonClient) fetchPublicStatusesChunk(ctx context.Context, userId string, count int, maxId string) ([]string, bool, string, error) {
+// Statuses of all visibilities are returned, so callers should filter out private and direct ones if needed.
+func (c *MastodonClient) fetchStatusesChunk(ctx context.Context, userId string, count int, maxId string) ([]Post, bool, string, error) {
 	url := c.buildUrl(fmt.Sprintf("/api/v1/accounts/%s/statuses?limit=%d&exclude_reblogs=1&exclude_replies=1", userId, count))
 	if maxId != "" {
 		url = fmt.Sprintf("%s&max_id=%s", url, maxId)
@@ -85,9 +86,16 @@ func (c *MastodonClient) fetchPublicStatusesChunk(ctx context.Context, userId st
 	}
 
 	statuses := []struct {
-		Id         string `json:"id"`
-		Content    string `json:"content"`
-		Visibility string `json:"visibility"`
+		Id          string    `json:"id"`
+		Content     string    `json:"content"`
+		Visibility  string    `json:"visibility"`
+		CreatedAt   time.Time `json:"created_at"`
+		Language    string    `json:"language"`
+		SpoilerText string    `json:"spoiler_text"`
+		InReplyToId string    `json:"in_reply_to_id"`
+		Account     struct {
+			Acct string `json:"acct"`
+		} `json:"account"`
 	}{}
 	if err := json.Unmarshal(bytes, &statuses); err != nil {
 		return nil, false, "", fmt.Errorf("unmarshal response: %w(%s)", err, bytes)
@@ -98,42 +106,71 @@ func (c *MastodonClient) fetchPublicStatusesChunk(ctx context.Context, userId st
 	}
 
 	tagPattern := regexp.MustCompile(`<[^>]*?>`)
-	result := make([]string, 0, len(statuses))
+	result := make([]Post, 0, len(statuses))
 	for _, v := range statuses {
-		if v.Visibility == MastodonStatusPrivate || v.Visibility == MastodonStatusDirect {
-			continue
-		}
 		// remove tags
 		body := html.UnescapeString(tagPattern.ReplaceAllLiteralString(v.Content, ""))
-		result = append(result, body)
+		result = append(result, Post{
+			ID:          v.Id,
+			Body:        body,
+			CreatedAt:   v.CreatedAt,
+			Visibility:  Visibility(v.Visibility),
+			Language:    v.Language,
+			Author:      v.Account.Acct,
+			SpoilerText: v.SpoilerText,
+			InReplyToID: v.InReplyToId,
+		})
 	}
 	return result, true, statuses[len(statuses)-1].Id, nil
 }
 
 func (c *MastodonClient) FetchUserId(ctx context.Context) (string, error) {
+	account, err := c.verifyCredentials(ctx)
+	if err != nil {
+		return "", err
+	}
+	return account.Id, nil
+}
+
+// Returns the account of the access token as "@username@host".
+func (c *MastodonClient) AccountName(ctx context.Context) (string, error) {
+	account, err := c.verifyCredentials(ctx)
+	if err != nil {
+		return "", err
+	}
+	host := c.Origin
+	if u, err := url.Parse(c.Origin); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	return fmt.Sprintf("@%s@%s", account.UserName, host), nil
+}
+
+type mastodonAccount struct {
+	Id       string `json:"id"`
+	UserName string `json:"username"`
+}
+
+func (c *MastodonClient) verifyCredentials(ctx context.Context) (*mastodonAccount, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", c.buildUrl("/api/v1/accounts/verify_credentials"), nil)
 	if err != nil {
-		return "", fmt.Errorf("create request: %w", err)
+		return nil, fmt.Errorf("create request: %w", err)
 	}
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.AccessToken))
 	res, err := c.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("get account details: %w", err)
+		return nil, fmt.Errorf("get account details: %w", err)
 	}
 	defer res.Body.Close()
 	bytes, err := io.ReadAll(res.Body)
 	if err != nil {
-		return "", nil
+		return nil, fmt.Errorf("read response: %w", err)
 	}
 
-	account := &struct {
-		Id       string `json:"id"`
-		UserName string `json:"username"`
-	}{}
+	account := &mastodonAccount{}
 	if err := json.Unmarshal(bytes, account); err != nil {
-		return "", fmt.Errorf("unmarshal response: %w", err)
+		return nil, fmt.Errorf("unmarshal response: %w", err)
 	}
-	return account.Id, nil
+	return account, nil
 }
 
 // Posts toot and returns created status id.
@@ -5,17 +5,28 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"reflect"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/paralleltree/markov-bot-go/blog"
 	"github.com/paralleltree/markov-bot-go/lib"
 )
 
 type mastodonStatus struct {
-	Id         string `json:"id"`
-	Content    string `json:"content"`
-	Visibility string `json:"visibility"`
+	Id          string          `json:"id"`
+	Content     string          `json:"content"`
+	Visibility  string          `json:"visibility"`
+	CreatedAt   string          `json:"created_at,omitempty"`
+	Language    string          `json:"language,omitempty"`
+	SpoilerText string          `json:"spoiler_text,omitempty"`
+	InReplyToId string          `json:"in_reply_to_id,omitempty"`
+	Account     mastodonAccount `json:"account"`
+}
+
+type mastodonAccount struct {
+	Acct string `json:"acct"`
 }
 
 func TestMastodonClient_CreateStatus(t *testing.T) {
@@ -101,9 +112,14 @@ func TestMastodonClient_FetchLatestPublicStatuses_RequestsSpecifiedCount(t *test
 		Visibility: "unlisted",
 	}
 	publicStatus := mastodonStatus{
-		Id:         oldestStatusId,
-		Content:    "1",
-		Visibility: "public",
+		Id:          oldestStatusId,
+		Content:     "<p>1</p>",
+		Visibility:  "public",
+		CreatedAt:   "2024-01-02T03:04:05.000Z",
+		Language:    "ja",
+		SpoilerText: "cw",
+		InReplyToId: "10",
+		Account:     mastodonAccount{Acct: "alice"},
 	}
 
 	responseStatuses := []mastodonStatus{
@@ -112,9 +128,21 @@ func TestMastodonClient_FetchLatestPublicStatuses_RequestsSpecifiedCount(t *test
 		unlistedStatus,
 		publicStatus,
 	}
-	wantStatuses := []mastodonStatus{
-		unlistedStatus,
-		publicStatus,
+	// statuses of all visibilities are returned
+	wantPosts := []blog.Post{
+		{ID: "4", Body: "4", Visibility: blog.VisibilityDirect},
+		{ID: "3", Body: "3", Visibility: blog.VisibilityPrivate},
+		{ID: "2", Body: "2", Visibility: blog.VisibilityUnlisted},
+		{
+			ID:          oldestStatusId,
+			Body:        "1",
+			CreatedAt:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			Visibility:  blog.VisibilityPublic,
+			Language:    "ja",
+			Author:      "alice",
+			SpoilerText: "cw",
+			InReplyToID: "10",
+		},
 	}
 
 	mux.HandleFunc(fmt.Sprintf("/api/v1/accounts/%s/statuses", wantAccountId), func(w http.ResponseWriter, r *http.Request) {
@@ -138,17 +166,10 @@ func TestMastodonClient_FetchLatestPublicStatuses_RequestsSpecifiedCount(t *test
 
 	client := blog.NewMastodonClientWithHttpClient(wantHost, wantAccessToken, "", httpClient)
 	iterator := client.GetPostsFetcher(ctx)
-	gotStatuses := consumeIterator(t, iterator, len(wantStatuses))
+	gotPosts := consumeIterator(t, iterator, len(wantPosts))
 
-	if len(wantStatuses) != len(gotStatuses) {
-		t.Fatalf("unexpected result: expected %v items, but got %v items", len(wantStatuses), len(gotStatuses))
-	}
-
-	for i, wantStatus := range wantStatuses {
-		gotStatus := gotStatuses[i]
-		if wantStatus.Content != gotStatus {
-			t.Fatalf("unexpected status: expected %v, but got %v", wantStatus.Content, gotStatus)
-		}
+	if !reflect.DeepEqual(wantPosts, gotPosts) {
+		t.Fatalf("unexpected result: expected %+v, but got %+v", wantPosts, gotPosts)
 	}
 }
 
@@ -224,7 +245,7 @@ func TestMastodonClient_FetchLatestPublicStatuses_ReturnsPublicStatusesWithPagin
 
 	for i, wantStatus := range allStatuses {
 		gotStatus := gotStatuses[i]
-		if wantStatus.Content != gotStatus {
+		if wantStatus.Content != gotStatus.Body {
 			t.Fatalf("unexpected status: expected %v, but got %v", wantStatus.Content, gotStatus)
 		}
 	}
@@ -294,6 +315,27 @@ func TestMastodonClient_FetchUserId_ReturnsUserId(t *testing.T) {
 	}
 }
 
+func TestMastodonClient_AccountName_ReturnsUserNameWithHost(t *testing.T) {
+	httpClient, mux, teardown := newTestServer()
+	defer teardown()
+
+	ctx := context.Background()
+	wantHost := "foo.net"
+	wantAccessToken := "token"
+	wantAuthorizationHeader := fmt.Sprintf("Bearer %s", wantAccessToken)
+
+	inflateVerifyCredentialsHandler(t, mux, wantHost, wantAuthorizationHeader, "123")
+
+	client := blog.NewMastodonClientWithHttpClient(wantHost, wantAccessToken, "", httpClient)
+	got, err := client.AccountName(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error while getting account name: %v", err)
+	}
+	if want := "@test@foo.net"; want != got {
+		t.Fatalf("unexpected account name: expected %v, but got %v", want, got)
+	}
+}
+
 func inflateVerifyCredentialsHandler(t *testing.T, mux *http.ServeMux, wantHost, wantAuthorizationHeader, wantId string) {
 	mux.HandleFunc("/api/v1/accounts/verify_credentials", func(w http.ResponseWriter, r *http.Request) {
 		gotHost := r.URL.Host
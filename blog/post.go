@@ -0,0 +1,56 @@
+package blog
+
+import (
+	"time"
+
+	"github.com/paralleltree/markov-bot-go/lib"
+)
+
+type Visibility string
+
+const (
+	VisibilityPublic   Visibility = MastodonStatusPublic
+	VisibilityUnlisted Visibility = MastodonStatusUnlisted
+	VisibilityPrivate  Visibility = MastodonStatusPrivate
+	VisibilityDirect   Visibility = MastodonStatusDirect
+)
+
+// Post is a status fetched from a blog.
+// Fields other than Body are zero values if the source does not provide them.
+type Post struct {
+	ID string
+	// The text of the post without markups.
+	Body      string
+	CreatedAt time.Time
+	// Empty if the source has no concept of visibility, which is regarded as public.
+	Visibility Visibility
+	// ISO 639 language code of the post.
+	Language string
+	// The account name of the author.
+	Author string
+	// The content warning shown instead of the body.
+	SpoilerText string
+	// The ID of the post this post replies to.
+	InReplyToID string
+}
+
+// Returns true if the post is visible to anyone, including unlisted posts.
+func (p Post) IsPublic() bool {
+	return p.Visibility != VisibilityPrivate && p.Visibility != VisibilityDirect
+}
+
+// Converts a fetcher of texts into a fetcher of posts having only bodies,
+// so that simple sources can be written without building posts.
+func PostsFromStrings(fetcher lib.ChunkIteratorFunc[string]) lib.ChunkIteratorFunc[Post] {
+	return func() ([]Post, bool, error) {
+		texts, hasNext, err := fetcher()
+		if err != nil {
+			return nil, false, err
+		}
+		posts := make([]Post, 0, len(texts))
+		for _, v := range texts {
+			posts = append(posts, Post{Body: v})
+		}
+		return posts, hasNext, nil
+	}
+}
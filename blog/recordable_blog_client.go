@@ -7,19 +7,27 @@ import (
 )
 
 type recordableBlogClient struct {
-	contents        []string
+	contents        []Post
 	contentsFetched bool
 	PostedContents  []string
 }
 
 func NewRecordableBlogClient(contents []string) *recordableBlogClient {
+	posts := make([]Post, 0, len(contents))
+	for _, v := range contents {
+		posts = append(posts, Post{Body: v})
+	}
+	return NewRecordableBlogClientWithPosts(posts)
+}
+
+func NewRecordableBlogClientWithPosts(posts []Post) *recordableBlogClient {
 	return &recordableBlogClient{
-		contents: contents,
+		contents: posts,
 	}
 }
 
-func (f *recordableBlogClient) GetPostsFetcher(ctx context.Context) lib.ChunkIteratorFunc[string] {
-	return func() ([]string, bool, error) {
+func (f *recordableBlogClient) GetPostsFetcher(ctx context.Context) lib.ChunkIteratorFunc[Post] {
+	return func() ([]Post, bool, error) {
 		if f.contentsFetched {
 			return nil, false, nil
 		}
@@ -16,9 +16,9 @@ func NewStdIOClient() BlogClient {
 	return &stdIOClient{}
 }
 
-func (c *stdIOClient) GetPostsFetcher(ctx context.Context) lib.ChunkIteratorFunc[string] {
+func (c *stdIOClient) GetPostsFetcher(ctx context.Context) lib.ChunkIteratorFunc[Post] {
 	stdin := bufio.NewScanner(os.Stdin)
-	return func() ([]string, bool, error) {
+	return PostsFromStrings(func() ([]string, bool, error) {
 		hasNext := stdin.Scan()
 		if !hasNext {
 			return nil, false, nil
@@ -29,7 +29,7 @@ func (c *stdIOClient) GetPostsFetcher(ctx context.Context) lib.ChunkIteratorFunc
 		}
 
 		return []string{stdin.Text()}, true, nil
-	}
+	})
 }
 
 func (c *stdIOClient) CreatePost(ctx context.Context, body string) error {
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/paralleltree/markov-bot-go/handler"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	OutputFileKey = "output-file"
+	InputFileKey  = "input-file"
+	TokenKey      = "token"
+	DepthKey      = "depth"
+)
+
+func newExportCommand(modelFileFlag cli.Flag) *cli.Command {
+	return &cli.Command{
+		Name:  "export",
+		Usage: "Writes built chain model in a human-readable format",
+		Flags: []cli.Flag{
+			modelFileFlag,
+			&cli.StringFlag{
+				Name:  FormatKey,
+				Usage: "specifies the output format: tsv, dot or json",
+				Value: string(handler.ExportFormatTSV),
+			},
+			&cli.StringFlag{
+				Name:  OutputFileKey,
+				Usage: "Write to `FILE` instead of stdout.",
+			},
+			&cli.StringFlag{
+				Name:  TokenKey,
+				Usage: "specifies the token at the center of the graph in dot format",
+			},
+			&cli.IntFlag{
+				Name:  DepthKey,
+				Usage: "specifies the number of transitions around the token to include in the graph",
+				Value: 1,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			var w io.Writer = os.Stdout
+			if c.IsSet(OutputFileKey) {
+				f, err := os.Create(c.String(OutputFileKey))
+				if err != nil {
+					return fmt.Errorf("create output file: %w", err)
+				}
+				defer f.Close()
+				w = f
+			}
+
+			store, err := openModelStore(c)
+			if err != nil {
+				return fmt.Errorf("open model store: %w", err)
+			}
+			return handler.ExportModel(
+				c.Context,
+				store,
+				w,
+				handler.WithExportFormat(handler.ExportFormat(c.String(FormatKey))),
+				handler.WithNeighborhood(c.String(TokenKey), c.Int(DepthKey)),
+			)
+		},
+	}
+}
+
+func newImportCommand(modelFileFlag cli.Flag) *cli.Command {
+	return &cli.Command{
+		Name:  "import",
+		Usage: "Saves chain model read from a file exported in tsv format",
+		Flags: []cli.Flag{
+			modelFileFlag,
+			&cli.StringFlag{
+				Name:  InputFileKey,
+				Usage: "Read from `FILE` instead of stdin.",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			var r io.Reader = os.Stdin
+			if c.IsSet(InputFileKey) {
+				f, err := os.Open(c.String(InputFileKey))
+				if err != nil {
+					return fmt.Errorf("open input file: %w", err)
+				}
+				defer f.Close()
+				r = f
+			}
+
+			unlock, err := lockModelFile(c)
+			if err != nil {
+				return fmt.Errorf("lock model file: %w", err)
+			}
+			defer unlock()
+
+			store, err := openModelStore(c)
+			if err != nil {
+				return fmt.Errorf("open model store: %w", err)
+			}
+			return handler.ImportModel(c.Context, r, store)
+		},
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/paralleltree/markov-bot-go/handler"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	PostIDKey  = "post-id"
+	DeletedKey = "deleted"
+)
+
+func newForgetCommand(configFileFlag, modelFileFlag cli.Flag) *cli.Command {
+	return &cli.Command{
+		Name:  "forget",
+		Usage: "Removes contributions of posts from chain model built with provenance",
+		Flags: []cli.Flag{
+			configFileFlag,
+			modelFileFlag,
+			&cli.StringSliceFlag{
+				Name:  PostIDKey,
+				Usage: "specifies the ID of the post to forget. Can be repeated.",
+			},
+			&cli.BoolFlag{
+				Name:  DeletedKey,
+				Usage: "forgets posts deleted on the source account in the configuration file",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			ids := c.StringSlice(PostIDKey)
+			if len(ids) == 0 && !c.Bool(DeletedKey) {
+				return fmt.Errorf("either %s or %s is required", PostIDKey, DeletedKey)
+			}
+
+			unlock, err := lockModelFile(c)
+			if err != nil {
+				return fmt.Errorf("lock model file: %w", err)
+			}
+			defer unlock()
+
+			store, err := openModelStore(c)
+			if err != nil {
+				return fmt.Errorf("open model store: %w", err)
+			}
+			forgotten := []string{}
+			if len(ids) > 0 {
+				res, err := handler.ForgetPosts(c.Context, store, ids)
+				if err != nil {
+					return fmt.Errorf("forget posts: %w", err)
+				}
+				forgotten = append(forgotten, res...)
+			}
+			if c.Bool(DeletedKey) {
+				conf, err := LoadBotConfigFromFile(c.Context, c.String(ConfigFileKey))
+				if err != nil {
+					return fmt.Errorf("load config: %w", err)
+				}
+				res, err := handler.ForgetDeletedPosts(c.Context, conf.FetchClient, store)
+				if err != nil {
+					return fmt.Errorf("forget deleted posts: %w", err)
+				}
+				forgotten = append(forgotten, res...)
+			}
+
+			for _, id := range forgotten {
+				fmt.Fprintln(os.Stdout, id)
+			}
+			if len(forgotten) == 0 {
+				fmt.Fprintln(os.Stderr, "no posts are forgotten; the model may be built without provenance")
+			}
+			return nil
+		},
+	}
+}
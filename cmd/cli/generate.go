@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/paralleltree/markov-bot-go/config"
+	"github.com/paralleltree/markov-bot-go/handler"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	CountKey         = "count"
+	WordKey          = "word"
+	MaxWordsCountKey = "max-words-count"
+	GreedyKey        = "greedy"
+	TemperatureKey   = "temperature"
+	TopKKey          = "top-k"
+	TopPKey          = "top-p"
+	ScoresKey        = "scores"
+	InteractiveKey   = "interactive"
+)
+
+func newGenerateCommand(configFileFlag, modelFileFlag cli.Flag) *cli.Command {
+	return &cli.Command{
+		Name:  "generate",
+		Usage: "Prints texts generated from built chain without posting them",
+		Flags: []cli.Flag{
+			configFileFlag,
+			modelFileFlag,
+			&cli.IntFlag{
+				Name:  CountKey,
+				Usage: "specifies the number of texts to generate",
+				Value: 5,
+			},
+			&cli.StringFlag{
+				Name:  WordKey,
+				Usage: "generates only texts containing `WORD`",
+			},
+			&cli.IntFlag{
+				Name:  MinWordsCountKey,
+				Usage: "specifies the minimum number of words",
+			},
+			&cli.IntFlag{
+				Name:  MaxWordsCountKey,
+				Usage: "specifies the maximum number of words. Zero means no limit",
+			},
+			&cli.BoolFlag{
+				Name:  GreedyKey,
+				Usage: "always chooses the most frequent word",
+			},
+			&cli.Float64Flag{
+				Name:  TemperatureKey,
+				Usage: "specifies the sampling temperature",
+			},
+			&cli.IntFlag{
+				Name:  TopKKey,
+				Usage: "samples from the k most frequent words",
+			},
+			&cli.Float64Flag{
+				Name:  TopPKey,
+				Usage: "samples from the most frequent words whose cumulative probability reaches p",
+			},
+			&cli.Int64Flag{
+				Name:  SeedKey,
+				Usage: "specifies the random seed to reproduce the same texts from the same model (if unset, a new seed is printed to stderr)",
+			},
+			&cli.BoolFlag{
+				Name:  ScoresKey,
+				Usage: "prints scores of generated texts",
+			},
+			&cli.BoolFlag{
+				Name:  InteractiveKey,
+				Usage: "reads words from stdin and prints texts containing each word",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			conf := config.DefaultChainConfig()
+			if c.IsSet(ConfigFileKey) {
+				botConf, err := LoadBotConfigFromFile(c.Context, c.String(ConfigFileKey))
+				if err != nil {
+					return fmt.Errorf("load config: %w", err)
+				}
+				conf = botConf.ChainConfig
+			}
+			overrideChainConfigFromCli(&conf, c)
+			overrideSamplingConfigFromCli(&conf, c)
+
+			store, err := openModelStore(c)
+			if err != nil {
+				return fmt.Errorf("open model store: %w", err)
+			}
+			opts := append(
+				conf.GenerateOptions(),
+				handler.WithCandidatesCount(c.Int(CountKey)),
+				handler.WithMaxWordsCount(c.Int(MaxWordsCountKey)),
+				handler.WithRand(randFromCli(c)),
+			)
+			sampler, err := handler.NewSampler(c.Context, store, opts...)
+			if err != nil {
+				return fmt.Errorf("new sampler: %w", err)
+			}
+
+			if c.Bool(InteractiveKey) {
+				return runGenerateREPL(os.Stdin, os.Stdout, sampler, c.Bool(ScoresKey))
+			}
+			return writeSamples(os.Stdout, sampler.Sample(c.String(WordKey)), c.Bool(ScoresKey))
+		},
+	}
+}
+
+func overrideSamplingConfigFromCli(conf *config.ChainConfig, c *cli.Context) {
+	if c.IsSet(GreedyKey) {
+		conf.Greedy = c.Bool(GreedyKey)
+	}
+	if c.IsSet(TemperatureKey) {
+		conf.Temperature = c.Float64(TemperatureKey)
+	}
+	if c.IsSet(TopKKey) {
+		conf.TopK = c.Int(TopKKey)
+	}
+	if c.IsSet(TopPKey) {
+		conf.TopP = c.Float64(TopPKey)
+	}
+}
+
+// Reads a word per line and prints texts containing it until EOF.
+// An empty line prints texts without restriction.
+func runGenerateREPL(r io.Reader, w io.Writer, sampler *handler.Sampler, withScores bool) error {
+	scanner := bufio.NewScanner(r)
+	for {
+		if _, err := fmt.Fprint(w, "> "); err != nil {
+			return err
+		}
+		if !scanner.Scan() {
+			break
+		}
+		samples := sampler.Sample(strings.TrimSpace(scanner.Text()))
+		if len(samples) == 0 {
+			if _, err := fmt.Fprintln(w, "(no text found)"); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := writeSamples(w, samples, withScores); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read input: %w", err)
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+func writeSamples(w io.Writer, samples []handler.Candidate, withScores bool) error {
+	for _, s := range samples {
+		var err error
+		if withScores {
+			_, err = fmt.Fprintf(w, "score=%.3f perplexity=%.3f words=%d\t%s\n", s.Score, s.Perplexity, s.WordsCount, s.Text)
+		} else {
+			_, err = fmt.Fprintln(w, s.Text)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/paralleltree/markov-bot-go/blog"
+	"github.com/paralleltree/markov-bot-go/handler"
+	"github.com/paralleltree/markov-bot-go/markov"
+	"github.com/paralleltree/markov-bot-go/morpheme"
+	"github.com/paralleltree/markov-bot-go/persistence"
+)
+
+func TestRunGenerateREPL_PrintsTextsForEachWord(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	fetchClient := blog.NewRecordableBlogClient([]string{"アルミ缶"})
+	store := persistence.NewMemoryStore()
+	if err := handler.BuildChain(ctx, fetchClient, morpheme.NewGraphemeAnalyzer(), store, handler.WithTokenUnit(markov.UnitCharacter)); err != nil {
+		t.Fatalf("BuildChain() should not return error, but got: %v", err)
+	}
+	sampler, err := handler.NewSampler(ctx, store)
+	if err != nil {
+		t.Fatalf("NewSampler() should not return error, but got: %v", err)
+	}
+	out := &bytes.Buffer{}
+
+	// act
+	err = runGenerateREPL(strings.NewReader("缶\nミカン\n\n"), out, sampler, false)
+
+	// assert
+	if err != nil {
+		t.Fatalf("runGenerateREPL() should not return error, but got: %v", err)
+	}
+	want := "> アルミ缶\n> (no text found)\n> アルミ缶\n> \n"
+	if want != out.String() {
+		t.Errorf("unexpected output: want %q, but got %q", want, out.String())
+	}
+}
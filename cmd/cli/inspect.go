@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/paralleltree/markov-bot-go/handler"
+	"github.com/paralleltree/markov-bot-go/markov"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	FormatKey = "format"
+	TopKey    = "top"
+)
+
+func newInspectCommand(modelFileFlag cli.Flag) *cli.Command {
+	return &cli.Command{
+		Name:  "inspect",
+		Usage: "Shows statistics of built chain model",
+		Flags: []cli.Flag{
+			modelFileFlag,
+			&cli.StringFlag{
+				Name:  FormatKey,
+				Usage: "specifies the output format: text or json",
+				Value: "text",
+			},
+			&cli.IntFlag{
+				Name:  TopKey,
+				Usage: "specifies the number of most frequent tokens to show",
+				Value: 10,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			store, err := openModelStore(c)
+			if err != nil {
+				return fmt.Errorf("open model store: %w", err)
+			}
+			report, err := handler.InspectModel(c.Context, store, c.Int(TopKey))
+			if err != nil {
+				return fmt.Errorf("inspect model: %w", err)
+			}
+
+			switch c.String(FormatKey) {
+			case "text":
+				return writeReportText(os.Stdout, report)
+			case "json":
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(report)
+			default:
+				return fmt.Errorf("unsupported format: %s", c.String(FormatKey))
+			}
+		},
+	}
+}
+
+func writeReportText(w io.Writer, r *handler.ModelReport) error {
+	ew := &errWriter{w: w}
+	ew.printf("modified at:          %s\n", r.ModifiedAt.Format(time.RFC3339))
+	if m := r.Metadata; m != nil {
+		ew.printf("built at:             %s\n", m.BuiltAt.Format(time.RFC3339))
+		ew.printf("bot name:             %s\n", m.BotName)
+		ew.printf("source:               %s %s\n", m.SourcePlatform, m.SourceAccount)
+		ew.printf("statuses:             %d fetched, %d kept\n", m.FetchedStatusesCount, m.KeptStatusesCount)
+		if m.NewestStatusID != "" || m.OldestStatusID != "" {
+			ew.printf("status ids:           %s to %s\n", m.OldestStatusID, m.NewestStatusID)
+		}
+		ew.printf("analyzer:             %s %s\n", m.Analyzer, m.Dictionary)
+		if p := m.Pruning; p != nil {
+			ew.printf("pruning:              states %d -> %d, transitions %d -> %d\n", p.StatesBefore, p.StatesAfter, p.TransitionsBefore, p.TransitionsAfter)
+		}
+	}
+	ew.printf("format version:       %d\n", r.Version)
+	ew.printf("unit:                 %s\n", r.Unit)
+	ew.printf("state size:           %d\n", r.StateSize)
+	ew.printf("variable order:       %t\n", r.VariableOrder)
+	ew.printf("vocabulary size:      %d\n", r.VocabularySize)
+	ew.printf("states:               %d\n", r.StatesCount)
+	ew.printf("transitions:          %d\n", r.TransitionsCount)
+	ew.printf("deterministic states: %.1f%%\n", r.DeterministicStatesRatio*100)
+	ew.printf("branching factors:\n")
+	for _, b := range r.BranchingFactors {
+		label := fmt.Sprintf("%d", b.Min)
+		if b.Min != b.Max {
+			label = fmt.Sprintf("%d-%d", b.Min, b.Max)
+		}
+		ew.printf("  %-8s %d\n", label, b.StatesCount)
+	}
+	writeTokenCounts(ew, "top tokens", r.TopTokens)
+	writeTokenCounts(ew, "top sentence starters", r.TopSentenceStarters)
+	return ew.err
+}
+
+func writeTokenCounts(ew *errWriter, title string, counts []markov.TokenCount) {
+	ew.printf("%s:\n", title)
+	for i, v := range counts {
+		ew.printf("  %2d. %q (%g)\n", i+1, v.Token, v.Occurrences)
+	}
+}
+
+// Keeps the first error of writes to write a report without checking errors on each line.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, args...)
+}
@@ -1,16 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
+	"math/rand"
 	"os"
 	"time"
 
 	"github.com/paralleltree/markov-bot-go/blog"
 	"github.com/paralleltree/markov-bot-go/config"
 	"github.com/paralleltree/markov-bot-go/handler"
-	"github.com/paralleltree/markov-bot-go/morpheme"
 	"github.com/paralleltree/markov-bot-go/persistence"
 	"github.com/urfave/cli/v2"
 )
@@ -23,6 +24,7 @@ const (
 	MinWordsCountKey    = "min-words-count"
 	ExpiresInKey        = "expires-in"
 	DryRunKey           = "dry-run"
+	SeedKey             = "seed"
 )
 
 func main() {
@@ -63,6 +65,11 @@ func main() {
 			EnvVars: []string{"MIN_WORDS_COUNT"},
 			Value:   1,
 		},
+		&cli.Int64Flag{
+			Name:    SeedKey,
+			Usage:   "specifies the random seed to reproduce the same post from the same model (if unset, a new seed is printed to stderr)",
+			EnvVars: []string{"SEED"},
+		},
 		&cli.IntFlag{
 			Name:    ExpiresInKey,
 			Usage:   "specifies the duration to expire the model in seconds.",
@@ -76,22 +83,41 @@ func main() {
 		modelFileFlag,
 	}
 
-	analyzer := morpheme.NewMecabAnalyzer("mecab-ipadic-neologd")
-
 	app := cli.App{
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:    KeepVersionsKey,
+				Usage:   "specifies the number of models to keep when the model file is a directory or a prefix of versions",
+				EnvVars: []string{"KEEP_VERSIONS"},
+				Value:   5,
+			},
+		},
 		Commands: []*cli.Command{
 			{
 				Name:  "build",
 				Usage: "Builds chain model and save it",
 				Flags: append(append([]cli.Flag{}, commonFlags...), buildingFlags...),
 				Action: func(c *cli.Context) error {
-					store := persistence.NewCompressedStore(persistence.NewFileStore(c.String(ModelFileKey)))
-					conf, err := LoadBotConfigFromFile(c.String(ConfigFileKey))
+					unlock, err := lockModelFile(c)
+					if err != nil {
+						return fmt.Errorf("lock model file: %w", err)
+					}
+					defer unlock()
+
+					store, err := openModelStore(c)
+					if err != nil {
+						return fmt.Errorf("open model store: %w", err)
+					}
+					conf, err := LoadBotConfigFromFile(c.Context, c.String(ConfigFileKey))
 					if err != nil {
 						return fmt.Errorf("load config: %w", err)
 					}
 					overrideChainConfigFromCli(&conf.ChainConfig, c)
-					return handler.BuildChain(c.Context, conf.FetchClient, analyzer, store, handler.WithFetchStatusCount(conf.FetchStatusCount), handler.WithStateSize(conf.StateSize))
+					analyzer, err := conf.NewAnalyzer()
+					if err != nil {
+						return fmt.Errorf("build analyzer: %w", err)
+					}
+					return handler.BuildChain(c.Context, conf.FetchClient, analyzer, store, conf.BuildOptions()...)
 				},
 			},
 			{
@@ -99,8 +125,11 @@ func main() {
 				Usage: "Posts new text from built chain",
 				Flags: append(append([]cli.Flag{}, commonFlags...), postingFlags...),
 				Action: func(c *cli.Context) error {
-					store := persistence.NewCompressedStore(persistence.NewFileStore(c.String(ModelFileKey)))
-					conf, err := LoadBotConfigFromFile(c.String(ConfigFileKey))
+					store, err := openModelStore(c)
+					if err != nil {
+						return fmt.Errorf("open model store: %w", err)
+					}
+					conf, err := LoadBotConfigFromFile(c.Context, c.String(ConfigFileKey))
 					if err != nil {
 						return fmt.Errorf("load config: %w", err)
 					}
@@ -108,7 +137,12 @@ func main() {
 					if c.Bool(DryRunKey) {
 						conf.PostClient = blog.NewStdIOClient()
 					}
-					return handler.GenerateAndPost(c.Context, conf.PostClient, store, handler.WithMinWordsCount(conf.MinWordsCount))
+					opts := append(
+						conf.GenerateOptions(),
+						handler.WithRand(randFromCli(c)),
+						handler.WithCandidatesWriter(candidatesWriterFromCli(c)),
+					)
+					return handler.GenerateAndPost(c.Context, conf.PostClient, store, opts...)
 				},
 			},
 			{
@@ -116,8 +150,15 @@ func main() {
 				Usage: "Posts new text after building chain if it expired",
 				Flags: append(append(append([]cli.Flag{}, commonFlags...), buildingFlags...), postingFlags...),
 				Action: func(c *cli.Context) error {
-					store := persistence.NewCompressedStore(persistence.NewFileStore(c.String(ModelFileKey)))
-					conf, err := LoadBotConfigFromFile(c.String(ConfigFileKey))
+					locker, err := openModelLocker(c)
+					if err != nil {
+						return fmt.Errorf("open model locker: %w", err)
+					}
+					store, err := openModelStore(c)
+					if err != nil {
+						return fmt.Errorf("open model store: %w", err)
+					}
+					conf, err := LoadBotConfigFromFile(c.Context, c.String(ConfigFileKey))
 					if err != nil {
 						return fmt.Errorf("load config: %w", err)
 					}
@@ -125,33 +166,46 @@ func main() {
 					if c.Bool(DryRunKey) {
 						conf.PostClient = blog.NewStdIOClient()
 					}
-					mod, ok, err := store.ModTime(c.Context)
+					analyzer, err := conf.NewAnalyzer()
 					if err != nil {
-						return fmt.Errorf("get modtime: %w", err)
+						return fmt.Errorf("build analyzer: %w", err)
 					}
-
-					buildChain := func() error {
-						return handler.BuildChain(c.Context, conf.FetchClient, analyzer, store, handler.WithFetchStatusCount(conf.FetchStatusCount), handler.WithStateSize(conf.StateSize))
+					lastPostStore, err := openStoreNextToModel(c, ".last_post")
+					if err != nil {
+						return fmt.Errorf("open last post store: %w", err)
 					}
 
-					if !ok {
-						// return an error if initial build fails
-						if err := buildChain(); err != nil {
-							return fmt.Errorf("build chain: %w", err)
-						}
+					opts := []handler.RunOption{
+						handler.WithExpiresIn(conf.ExpiresInDuration()),
+						handler.WithMinPostInterval(lastPostStore, conf.MinPostIntervalDuration()),
+						handler.WithBuildOptions(conf.BuildOptions()...),
+						handler.WithGenerateOptions(conf.GenerateOptions()...),
+						handler.WithGenerateOptions(
+							handler.WithRand(randFromCli(c)),
+							handler.WithCandidatesWriter(candidatesWriterFromCli(c)),
+						),
 					}
-
-					if float64(conf.ExpiresIn) < time.Since(mod).Seconds() {
-						// attempt to build chain if expired
-						// when building chain fails, it will use the existing chain
-						if err := buildChain(); err != nil {
-							fmt.Fprintf(os.Stderr, "build chain: %v\n", err)
+					// dry runs do not post actually
+					if c.Bool(DryRunKey) {
+						opts = append(opts, handler.WithoutRecordingPost())
+					}
+					if conf.ForgetDeleted {
+						checkedStore, err := openStoreNextToModel(c, ".forget_checked")
+						if err != nil {
+							return fmt.Errorf("open forget checked store: %w", err)
 						}
+						opts = append(opts, handler.WithForgetDeleted(checkedStore, conf.ForgetDeletedIntervalDuration()))
 					}
-
-					return handler.GenerateAndPost(c.Context, conf.PostClient, store, handler.WithMinWordsCount(conf.MinWordsCount))
+					// an overlapping run is skipped as in Lambda, instead of posting after waiting for the lock
+					return handler.Run(c.Context, locker.TryLock, conf.FetchClient, analyzer, conf.PostClient, store, opts...)
 				},
 			},
+			newGenerateCommand(configFileFlag, modelFileFlag),
+			newInspectCommand(modelFileFlag),
+			newExportCommand(modelFileFlag),
+			newImportCommand(modelFileFlag),
+			newForgetCommand(configFileFlag, modelFileFlag),
+			newVersionsCommand(modelFileFlag),
 		},
 	}
 
@@ -176,14 +230,63 @@ func overrideChainConfigFromCli(conf *config.ChainConfig, c *cli.Context) {
 	}
 }
 
-func LoadBotConfigFromFile(path string) (*config.BotConfig, error) {
-	f, err := os.Open(path)
+// Returns a random source seeded with the seed flag.
+// If the flag is not set, a new seed is used and printed to stderr so that the generation can be reproduced.
+func randFromCli(c *cli.Context) *rand.Rand {
+	if c.IsSet(SeedKey) {
+		return rand.New(rand.NewSource(c.Int64(SeedKey)))
+	}
+	seed := time.Now().UnixNano()
+	fmt.Fprintf(os.Stderr, "seed: %d\n", seed)
+	return rand.New(rand.NewSource(seed))
+}
+
+// Returns stderr to show candidates and their scores on dry run, or nil otherwise.
+func candidatesWriterFromCli(c *cli.Context) io.Writer {
+	if !c.Bool(DryRunKey) {
+		return nil
+	}
+	return os.Stderr
+}
+
+// Locks on S3 expire after this duration in case the process crashes. Builds must finish within it.
+const lockTTL = time.Hour
+
+// Locks the model file so that overlapping invocations do not build or modify the model at the same time.
+func lockModelFile(c *cli.Context) (func() error, error) {
+	locker, err := openModelLocker(c)
 	if err != nil {
-		return nil, fmt.Errorf("open config file: %w", err)
+		return nil, fmt.Errorf("open locker: %w", err)
 	}
-	defer f.Close()
+	return persistence.Lock(c.Context, locker)
+}
 
-	confBody, err := io.ReadAll(f)
+// Opens the lock of the model file.
+// The lock is held on a separate file because the model file is replaced on saving.
+func openModelLocker(c *cli.Context) (persistence.Locker, error) {
+	lockURL, err := persistence.AppendToKey(c.String(ModelFileKey), ".lock")
+	if err != nil {
+		return nil, fmt.Errorf("resolve lock url: %w", err)
+	}
+	return persistence.OpenLocker(lockURL, lockTTL)
+}
+
+// Opens the store next to the model file, whose URL is the one of the model file followed by suffix.
+func openStoreNextToModel(c *cli.Context, suffix string) (persistence.PersistentStore, error) {
+	u, err := persistence.AppendToKey(c.String(ModelFileKey), suffix)
+	if err != nil {
+		return nil, fmt.Errorf("resolve url: %w", err)
+	}
+	return persistence.Open(u)
+}
+
+// Loads the configuration from a path or a store URL accepted by persistence.Open.
+func LoadBotConfigFromFile(ctx context.Context, path string) (*config.BotConfig, error) {
+	store, err := persistence.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open config store: %w", err)
+	}
+	confBody, err := store.Load(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("read config file: %w", err)
 	}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/paralleltree/markov-bot-go/handler"
+	"github.com/paralleltree/markov-bot-go/markov"
+	"github.com/paralleltree/markov-bot-go/persistence"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	KeepVersionsKey = "keep-versions"
+)
+
+type versionedStore interface {
+	persistence.PersistentStore
+	Manifest(ctx context.Context) (*persistence.VersionManifest, error)
+	Rollback(ctx context.Context, id string) error
+	Unpin(ctx context.Context) error
+}
+
+// Opens the store of the model file, which is a path or a store URL accepted by persistence.Open.
+// Models at paths and file URLs without modifiers are compressed with gzip, and saved as versions if it is a directory.
+// Models at other URLs are saved as versions if the URL ends with "/", e.g. s3://bucket/bot/models/.
+func openModelStore(c *cli.Context) (persistence.PersistentStore, error) {
+	path := c.String(ModelFileKey)
+	if !strings.Contains(path, "://") {
+		return openModelFile(c, path, persistence.NewFileStore(path))
+	}
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("parse model file: %w", err)
+	}
+	if strings.HasSuffix(u.Path, "/") {
+		return openVersionedStore(c, path)
+	}
+	store, err := persistence.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	// file URLs are another form of paths
+	if u.Scheme == persistence.SchemeFile {
+		return openModelFile(c, u.Path, store)
+	}
+	return store, nil
+}
+
+// Returns the versioned store if the path is a directory, or the store of the file compressed with gzip.
+func openModelFile(c *cli.Context, path string, file persistence.PersistentStore) (persistence.PersistentStore, error) {
+	if stat, err := os.Stat(path); err == nil && stat.IsDir() {
+		return openVersionedStore(c, path)
+	}
+	return persistence.NewCompressedStore(file), nil
+}
+
+// Opens the versioned store under the directory or the prefix given by the model file.
+func openVersionedStore(c *cli.Context, location string) (versionedStore, error) {
+	store, err := persistence.OpenVersioned(
+		location,
+		persistence.WithKeepVersions(c.Int(KeepVersionsKey)),
+		persistence.WithVersionDescriber(handler.DescribeModel),
+		persistence.WithWarningWriter(os.Stderr),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("open versioned store: %w", err)
+	}
+	return store, nil
+}
+
+func newVersionsCommand(modelFileFlag cli.Flag) *cli.Command {
+	// returns the versioned store, failing if the model file is a path but not a directory
+	versionedStoreFromCli := func(c *cli.Context) (versionedStore, error) {
+		path := c.String(ModelFileKey)
+		if strings.Contains(path, "://") {
+			return openVersionedStore(c, path)
+		}
+		stat, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("stat model file: %w", err)
+		}
+		if !stat.IsDir() {
+			return nil, fmt.Errorf("%s is not a directory of versioned models", path)
+		}
+		return openVersionedStore(c, path)
+	}
+
+	return &cli.Command{
+		Name:  "versions",
+		Usage: "Manages versions of chain models saved in the directory or under the prefix of the model file",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "list",
+				Usage: "Lists saved versions from the oldest one",
+				Flags: []cli.Flag{modelFileFlag},
+				Action: func(c *cli.Context) error {
+					store, err := versionedStoreFromCli(c)
+					if err != nil {
+						return err
+					}
+					m, err := store.Manifest(c.Context)
+					if err != nil {
+						return fmt.Errorf("load manifest: %w", err)
+					}
+					return writeVersions(os.Stdout, m)
+				},
+			},
+			{
+				Name:      "rollback",
+				Usage:     "Activates the version and pins it so that builds do not replace it until unpinned",
+				ArgsUsage: "ID",
+				Flags:     []cli.Flag{modelFileFlag},
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 1 {
+						return fmt.Errorf("version id is required")
+					}
+					store, err := versionedStoreFromCli(c)
+					if err != nil {
+						return err
+					}
+					unlock, err := lockModelFile(c)
+					if err != nil {
+						return fmt.Errorf("lock model file: %w", err)
+					}
+					defer unlock()
+
+					return store.Rollback(c.Context, c.Args().First())
+				},
+			},
+			{
+				Name:  "unpin",
+				Usage: "Allows builds to save new versions again after rollback",
+				Flags: []cli.Flag{modelFileFlag},
+				Action: func(c *cli.Context) error {
+					store, err := versionedStoreFromCli(c)
+					if err != nil {
+						return err
+					}
+					unlock, err := lockModelFile(c)
+					if err != nil {
+						return fmt.Errorf("lock model file: %w", err)
+					}
+					defer unlock()
+
+					return store.Unpin(c.Context)
+				},
+			},
+		},
+	}
+}
+
+func writeVersions(w io.Writer, m *persistence.VersionManifest) error {
+	ew := &errWriter{w: w}
+	if m.Pinned {
+		ew.printf("pinned to %s\n", m.Active)
+	}
+	for _, v := range m.Versions {
+		marker := " "
+		if v.ID == m.Active {
+			marker = "*"
+		}
+		ew.printf("%s %s\t%s\t%d bytes uncompressed", marker, v.ID, v.SavedAt.Local().Format(time.RFC3339), v.Size)
+		metadata := markov.Metadata{}
+		if len(v.Metadata) > 0 && json.Unmarshal(v.Metadata, &metadata) == nil {
+			ew.printf("\tkept %d of %d statuses", metadata.KeptStatusesCount, metadata.FetchedStatusesCount)
+		}
+		ew.printf("\n")
+	}
+	return ew.err
+}
@@ -3,13 +3,14 @@ package main
 import (
 	"context"
 	"fmt"
+	"math/rand"
+	"net/url"
 	"os"
 	"time"
 
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/paralleltree/markov-bot-go/config"
 	"github.com/paralleltree/markov-bot-go/handler"
-	"github.com/paralleltree/markov-bot-go/morpheme"
 	"github.com/paralleltree/markov-bot-go/persistence"
 )
 
@@ -17,16 +18,73 @@ func main() {
 	lambda.Start(requestHandler)
 }
 
+// keeps the model decoded while the container is warm
+var modelCache = handler.NewModelCache()
+
+// model stores opened for events, reused while the container is warm since modelCache is tied to the store
+var modelStores = map[PostEvent]persistence.PersistentStore{}
+
 type PostEvent struct {
 	S3Region     string `json:"s3Region"`
 	S3BucketName string `json:"s3BucketName"`
 	S3KeyPrefix  string `json:"s3KeyPrefix"`
+	// The endpoint of an S3-compatible service such as MinIO, which usually requires path-style addressing.
+	S3Endpoint  string `json:"s3Endpoint"`
+	S3PathStyle bool   `json:"s3PathStyle"`
+	// Static credentials used instead of the ones of the function, e.g. the access keys of MinIO.
+	S3AccessKeyID     string `json:"s3AccessKeyId"`
+	S3SecretAccessKey string `json:"s3SecretAccessKey"`
+	// If positive, models are saved as versions under "models/" of the prefix, keeping this number of them.
+	// With modelUrl, the versions are saved under the prefix given by it instead.
+	ModelVersions int `json:"modelVersions"`
+
+	// Store URLs accepted by persistence.Open, which override the locations under the prefix.
+	ConfigURL   string `json:"configUrl"`
+	ModelURL    string `json:"modelUrl"`
+	LastPostURL string `json:"lastPostUrl"`
+	LockURL     string `json:"lockUrl"`
+	// Records the time deleted statuses were last checked with forget_deleted.
+	ForgetCheckedURL string `json:"forgetCheckedUrl"`
+}
+
+// Returns the URL if given, or the URL of the name under the prefix on S3 with the modifiers.
+func (e PostEvent) storeURL(override string, name string, modifiers string) (string, error) {
+	if override != "" {
+		return override, nil
+	}
+	if e.S3BucketName == "" {
+		return "", fmt.Errorf("either s3BucketName or the url of %s is required", name)
+	}
+	u := url.URL{
+		Scheme: "s3" + modifiers,
+		Host:   e.S3BucketName,
+		Path:   fmt.Sprintf("/%s/%s", e.S3KeyPrefix, name),
+	}
+	if e.S3AccessKeyID != "" || e.S3SecretAccessKey != "" {
+		u.User = url.UserPassword(e.S3AccessKeyID, e.S3SecretAccessKey)
+	}
+	query := url.Values{}
+	if e.S3Region != "" {
+		query.Set("region", e.S3Region)
+	}
+	if e.S3Endpoint != "" {
+		query.Set("endpoint", e.S3Endpoint)
+	}
+	if e.S3PathStyle {
+		query.Set("path_style", "true")
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
 }
 
 func requestHandler(ctx context.Context, e PostEvent) error {
-	confStore, err := persistence.NewS3Store(e.S3Region, e.S3BucketName, fmt.Sprintf("%s/config.yml", e.S3KeyPrefix))
+	confURL, err := e.storeURL(e.ConfigURL, "config.yml", "")
 	if err != nil {
-		return fmt.Errorf("new s3 store: %w", err)
+		return err
+	}
+	confStore, err := persistence.Open(confURL)
+	if err != nil {
+		return fmt.Errorf("open config store: %w", err)
 	}
 
 	conf, err := loadConfig(ctx, confStore)
@@ -34,51 +92,109 @@ func requestHandler(ctx context.Context, e PostEvent) error {
 		return fmt.Errorf("load config: %w", err)
 	}
 
-	s3Store, err := persistence.NewS3Store(e.S3Region, e.S3BucketName, fmt.Sprintf("%s/model", e.S3KeyPrefix))
+	modelStore, err := openModelStore(e)
+	if err != nil {
+		return fmt.Errorf("new model store: %w", err)
+	}
+
+	lockURL, err := e.storeURL(e.LockURL, "lock", "")
+	if err != nil {
+		return err
+	}
+	locker, err := persistence.OpenLocker(lockURL, lockTTL)
+	if err != nil {
+		return fmt.Errorf("open locker: %w", err)
+	}
+	lastPostURL, err := e.storeURL(e.LastPostURL, "last_post", "")
+	if err != nil {
+		return err
+	}
+	lastPostStore, err := persistence.Open(lastPostURL)
 	if err != nil {
-		return fmt.Errorf("new s3 store: %w", err)
+		return fmt.Errorf("open last post store: %w", err)
 	}
-	modelStore := persistence.NewCompressedStore(s3Store)
 
-	if err := run(ctx, conf, modelStore); err != nil {
+	forgetCheckedURL, err := e.storeURL(e.ForgetCheckedURL, "forget_checked", "")
+	if err != nil {
+		return err
+	}
+	forgetCheckedStore, err := persistence.Open(forgetCheckedURL)
+	if err != nil {
+		return fmt.Errorf("open forget checked store: %w", err)
+	}
+
+	if err := run(ctx, conf, modelStore, locker, lastPostStore, forgetCheckedStore); err != nil {
 		return fmt.Errorf("run: %w", err)
 	}
 
 	return nil
 }
 
-func run(ctx context.Context, conf *config.BotConfig, modelStore persistence.PersistentStore) error {
-	analyzer := morpheme.NewMecabAnalyzer("mecab-ipadic-neologd")
-
-	mod, ok, err := modelStore.ModTime(ctx)
-	if err != nil {
-		return fmt.Errorf("get modtime: %w", err)
+// Returns the model store opened for the same event before, or a new one.
+func openModelStore(e PostEvent) (persistence.PersistentStore, error) {
+	if store, ok := modelStores[e]; ok {
+		return store, nil
 	}
-
-	buildChain := func() error {
-		return handler.BuildChain(ctx, conf.FetchClient, analyzer, modelStore, handler.WithFetchStatusCount(conf.FetchStatusCount), handler.WithStateSize(conf.StateSize))
+	store, err := newModelStore(e)
+	if err != nil {
+		return nil, err
 	}
+	modelStores[e] = store
+	return store, nil
+}
 
-	if !ok {
-		// return an error if initial build fails
-		if err := buildChain(); err != nil {
-			return fmt.Errorf("build chain: %w", err)
+func newModelStore(e PostEvent) (persistence.PersistentStore, error) {
+	if e.ModelVersions <= 0 {
+		modelURL, err := e.storeURL(e.ModelURL, "model", "+"+persistence.ModifierGzip)
+		if err != nil {
+			return nil, err
 		}
+		return persistence.Open(modelURL)
 	}
-
-	if float64(conf.ExpiresIn) < time.Since(mod).Seconds() {
-		// attempt to build chain if expired
-		// when building chain fails, it will use the existing chain
-		if err := buildChain(); err != nil {
-			fmt.Fprintf(os.Stderr, "build chain: %v\n", err)
-		}
+	modelsURL, err := e.storeURL(e.ModelURL, "models/", "")
+	if err != nil {
+		return nil, err
 	}
+	store, err := persistence.OpenVersioned(
+		modelsURL,
+		persistence.WithKeepVersions(e.ModelVersions),
+		persistence.WithVersionDescriber(handler.DescribeModel),
+		persistence.WithWarningWriter(os.Stderr),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
 
-	if err := handler.GenerateAndPost(ctx, conf.PostClient, modelStore, handler.WithMinWordsCount(conf.MinWordsCount)); err != nil {
-		return fmt.Errorf("generate and post: %w", err)
+// The maximum timeout of Lambda functions, so that the lock outlives the invocation holding it.
+const lockTTL = 15 * time.Minute
+
+func run(ctx context.Context, conf *config.BotConfig, modelStore persistence.PersistentStore, locker persistence.Locker, lastPostStore persistence.PersistentStore, forgetCheckedStore persistence.PersistentStore) error {
+	analyzer, err := conf.NewAnalyzer()
+	if err != nil {
+		return fmt.Errorf("build analyzer: %w", err)
 	}
 
-	return nil
+	// the seed is logged so that the post can be reproduced with the seed flag of the CLI
+	seed := time.Now().UnixNano()
+	fmt.Fprintf(os.Stderr, "seed: %d\n", seed)
+
+	opts := []handler.RunOption{
+		handler.WithExpiresIn(conf.ExpiresInDuration()),
+		handler.WithMinPostInterval(lastPostStore, conf.MinPostIntervalDuration()),
+		handler.WithBuildOptions(conf.BuildOptions()...),
+		handler.WithGenerateOptions(conf.GenerateOptions()...),
+		handler.WithGenerateOptions(
+			handler.WithModelCache(modelCache),
+			handler.WithRand(rand.New(rand.NewSource(seed))),
+		),
+	}
+	if conf.ForgetDeleted {
+		opts = append(opts, handler.WithForgetDeleted(forgetCheckedStore, conf.ForgetDeletedIntervalDuration()))
+	}
+	// concurrent invocations, e.g. retried ones, are skipped instead of waiting for the lock
+	return handler.Run(ctx, locker.TryLock, conf.FetchClient, analyzer, conf.PostClient, modelStore, opts...)
 }
 
 func loadConfig(ctx context.Context, store persistence.PersistentStore) (*config.BotConfig, error) {
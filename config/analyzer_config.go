@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/paralleltree/markov-bot-go/morpheme"
+)
+
+const (
+	AnalyzerMecab   = "mecab"
+	AnalyzerEnglish = "english"
+	// Routes Japanese sentences to MeCab and English sentences to the English tokenizer.
+	AnalyzerMixed = "mixed"
+)
+
+const defaultMecabDictionary = "mecab-ipadic-neologd"
+
+type AnalyzerConfig struct {
+	// The name of analyzer. If empty, AnalyzerMecab is used.
+	Analyzer string `yaml:"analyzer"`
+	// The name of MeCab dictionary placed in the directory returned by `mecab-config --dicdir`.
+	Dictionary string `yaml:"dictionary"`
+	// Names of preprocessing steps applied in order. See morpheme.NewPreprocessor for available steps.
+	// If empty, morpheme.DefaultPreprocessSteps is used.
+	Preprocess []string `yaml:"preprocess"`
+}
+
+func (c AnalyzerConfig) NewPreprocessor() (*morpheme.Preprocessor, error) {
+	if len(c.Preprocess) == 0 {
+		return morpheme.DefaultPreprocessor(), nil
+	}
+	return morpheme.NewPreprocessor(c.Preprocess...)
+}
+
+func (c AnalyzerConfig) NewAnalyzer() (morpheme.MorphemeAnalyzer, error) {
+	preprocessor, err := c.NewPreprocessor()
+	if err != nil {
+		return nil, fmt.Errorf("build preprocessor: %w", err)
+	}
+	dictionary := c.mecabDictionary()
+
+	switch strings.ToLower(c.Analyzer) {
+	case "", AnalyzerMecab:
+		return morpheme.NewMecabAnalyzer(dictionary, morpheme.WithPreprocessor(preprocessor)), nil
+
+	case AnalyzerEnglish:
+		return morpheme.NewEnglishAnalyzer(morpheme.WithPreprocessor(preprocessor)), nil
+
+	case AnalyzerMixed:
+		return morpheme.NewLanguageRoutingAnalyzer(
+			morpheme.NewMecabAnalyzer(dictionary, morpheme.WithoutPreprocessing()),
+			morpheme.NewEnglishAnalyzer(morpheme.WithoutPreprocessing()),
+			morpheme.WithPreprocessor(preprocessor),
+		), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported analyzer: %s", c.Analyzer)
+	}
+}
+
+func (c AnalyzerConfig) mecabDictionary() string {
+	if c.Dictionary == "" {
+		return defaultMecabDictionary
+	}
+	return c.Dictionary
+}
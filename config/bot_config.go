@@ -5,19 +5,29 @@ import (
 	"strings"
 
 	"github.com/paralleltree/markov-bot-go/blog"
+	"github.com/paralleltree/markov-bot-go/handler"
+	"github.com/paralleltree/markov-bot-go/markov"
+	"github.com/paralleltree/markov-bot-go/morpheme"
 	"gopkg.in/yaml.v3"
 )
 
 type BotConfig struct {
+	// The name to identify the bot in the model metadata.
+	Name        string
 	FetchClient blog.BlogClient
 	PostClient  blog.BlogClient
 	ChainConfig
+	AnalyzerConfig
+
+	source map[string]interface{}
 }
 
 type ConfigFile struct {
-	Input       map[string]interface{} `yaml:"input"`
-	Output      map[string]interface{} `yaml:"output"`
-	ChainConfig `yaml:",inline"`
+	Name           string                 `yaml:"name"`
+	Input          map[string]interface{} `yaml:"input"`
+	Output         map[string]interface{} `yaml:"output"`
+	ChainConfig    `yaml:",inline"`
+	AnalyzerConfig `yaml:",inline"`
 }
 
 func LoadBotConfig(body []byte) (*BotConfig, error) {
@@ -37,11 +47,75 @@ func LoadBotConfig(body []byte) (*BotConfig, error) {
 		return nil, fmt.Errorf("resolve post client: %w", err)
 	}
 
-	return &BotConfig{
-		FetchClient: fetchClient,
-		PostClient:  postClient,
-		ChainConfig: conf.ChainConfig,
-	}, nil
+	botConf := &BotConfig{
+		Name:           conf.Name,
+		source:         conf.Input,
+		FetchClient:    fetchClient,
+		PostClient:     postClient,
+		ChainConfig:    conf.ChainConfig,
+		AnalyzerConfig: conf.AnalyzerConfig,
+	}
+	if _, err := botConf.NewAnalyzer(); err != nil {
+		return nil, fmt.Errorf("resolve analyzer: %w", err)
+	}
+	return botConf, nil
+}
+
+// Returns the analyzer for the chain mode.
+// In character mode, texts are split into characters regardless of the analyzer setting.
+func (c *BotConfig) NewAnalyzer() (morpheme.MorphemeAnalyzer, error) {
+	switch c.Mode {
+	case "", ModeWord:
+		return c.AnalyzerConfig.NewAnalyzer()
+
+	case ModeCharacter:
+		preprocessor, err := c.AnalyzerConfig.NewPreprocessor()
+		if err != nil {
+			return nil, fmt.Errorf("build preprocessor: %w", err)
+		}
+		return morpheme.NewGraphemeAnalyzer(morpheme.WithPreprocessor(preprocessor)), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported mode: %s", c.Mode)
+	}
+}
+
+// Returns the metadata identifying the bot, its source and its analyzer to record in the built model.
+func (c *BotConfig) BuildMetadata() markov.Metadata {
+	// the source account is recorded on building, since it is identified by the access token
+	m := markov.Metadata{
+		BotName:        c.Name,
+		SourcePlatform: strings.ToLower(resolveMapValue[string](c.source, "platform")),
+	}
+
+	switch c.Mode {
+	case ModeCharacter:
+		m.Analyzer = "grapheme"
+	default:
+		m.Analyzer = strings.ToLower(c.Analyzer)
+		if m.Analyzer == "" {
+			m.Analyzer = AnalyzerMecab
+		}
+		if m.Analyzer != AnalyzerEnglish {
+			m.Dictionary = c.mecabDictionary()
+		}
+	}
+	return m
+}
+
+// Returns the options to build the chain as configured, including the metadata of the bot.
+func (c *BotConfig) BuildOptions() []handler.BuildChainOption {
+	return []handler.BuildChainOption{
+		handler.WithFetchStatusCount(c.FetchStatusCount),
+		handler.WithStateSize(c.StateSize),
+		handler.WithTokenUnit(c.TokenUnit()),
+		handler.WithVariableOrder(c.VariableOrder),
+		handler.WithConcurrency(c.BuildConcurrency),
+		handler.WithDecayHalfLife(c.DecayHalfLifeDuration()),
+		handler.WithMetadata(c.BuildMetadata()),
+		handler.WithPruning(c.PruneOptions()),
+		handler.WithProvenance(c.TrackProvenance),
+	}
 }
 
 func resolveBlogClient(conf map[string]interface{}) (blog.BlogClient, error) {
@@ -1,17 +1,147 @@
 package config
 
+import (
+	"time"
+
+	"github.com/paralleltree/markov-bot-go/handler"
+	"github.com/paralleltree/markov-bot-go/markov"
+)
+
+const (
+	ModeWord      = "word"
+	ModeCharacter = "character"
+)
+
 type ChainConfig struct {
-	StateSize        int `yaml:"state_size"`
-	FetchStatusCount int `yaml:"fetch_status_count"`
-	ExpiresIn        int `yaml:"expires_in"`
-	MinWordsCount    int `yaml:"min_words_count"`
+	// Either ModeWord or ModeCharacter. If empty, ModeWord is used.
+	// In character mode, StateSize and MinWordsCount are counted in characters.
+	Mode             string `yaml:"mode"`
+	StateSize        int    `yaml:"state_size"`
+	FetchStatusCount int    `yaml:"fetch_status_count"`
+	ExpiresIn        int    `yaml:"expires_in"`
+	MinWordsCount    int    `yaml:"min_words_count"`
+	// The number of workers analyzing statuses in parallel. If zero, the number of CPUs is used.
+	BuildConcurrency int `yaml:"build_concurrency"`
+	// If true, contexts shorter than StateSize are also stored,
+	// and generation backs off to them when the longest context lacks continuations.
+	VariableOrder           bool `yaml:"variable_order"`
+	BackoffMinContinuations int  `yaml:"backoff_min_continuations"`
+	BackoffMinOccurrences   int  `yaml:"backoff_min_occurrences"`
+	// Sampling strategy to choose the next word. See markov.SamplingOptions for details.
+	Greedy      bool    `yaml:"greedy"`
+	Temperature float64 `yaml:"temperature"`
+	TopK        int     `yaml:"top_k"`
+	TopP        float64 `yaml:"top_p"`
+	// The number of candidates to generate. The one with the highest score is posted.
+	// See handler.ScoringOptions for details.
+	CandidatesCount  int     `yaml:"candidates_count"`
+	TargetPerplexity float64 `yaml:"target_perplexity"`
+	LengthWeight     float64 `yaml:"length_weight"`
+	// Statuses are weighed by 0.5^(age/DecayHalfLife) in seconds so that recent ones dominate,
+	// where the age is measured from the build time.
+	// Weights are floored at 1/1024, reached after about 10 half-lives, so that old statuses are never dropped;
+	// statuses of a long-inactive account end up weighed equally at the floor.
+	// If zero, all statuses are weighed equally.
+	DecayHalfLife int `yaml:"decay_half_life"`
+	// Limits to reduce the size of the model. See markov.PruneOptions for details.
+	PruneMinOccurrences float64 `yaml:"prune_min_occurrences"`
+	PruneMaxChildren    int     `yaml:"prune_max_children"`
+	PruneMaxStates      int     `yaml:"prune_max_states"`
+	// If true, the model records which status contributed each sentence, so that statuses can be forgotten.
+	TrackProvenance bool `yaml:"track_provenance"`
+	// If true, statuses deleted on the source are forgotten before posting from a model which is not rebuilt.
+	// Requires TrackProvenance and a Mastodon source, whose status IDs increase over time.
+	ForgetDeleted bool `yaml:"forget_deleted"`
+	// Deleted statuses are checked once in this interval in seconds, since it fetches statuses back to the oldest one.
+	// If zero, ExpiresIn is used.
+	ForgetDeletedInterval int `yaml:"forget_deleted_interval"`
+	// Runs within this interval in seconds after the last post skip posting, e.g. retried invocations.
+	// Defaults to zero, with which every run posts.
+	MinPostInterval int `yaml:"min_post_interval"`
 }
 
 func DefaultChainConfig() ChainConfig {
 	return ChainConfig{
+		Mode:             ModeWord,
 		StateSize:        3,
 		FetchStatusCount: 200,
 		ExpiresIn:        60 * 60 * 24,
 		MinWordsCount:    1,
+
+		BackoffMinContinuations: markov.DefaultBackoffOptions().MinContinuations,
+		BackoffMinOccurrences:   markov.DefaultBackoffOptions().MinOccurrences,
+
+		Temperature: markov.DefaultSamplingOptions().Temperature,
+
+		CandidatesCount: 1,
+	}
+}
+
+func (c ChainConfig) TokenUnit() markov.TokenUnit {
+	if c.Mode == ModeCharacter {
+		return markov.UnitCharacter
 	}
+	return markov.UnitWord
+}
+
+func (c ChainConfig) BackoffOptions() markov.BackoffOptions {
+	return markov.BackoffOptions{
+		MinContinuations: c.BackoffMinContinuations,
+		MinOccurrences:   c.BackoffMinOccurrences,
+	}
+}
+
+func (c ChainConfig) SamplingOptions() markov.SamplingOptions {
+	return markov.SamplingOptions{
+		Greedy:      c.Greedy,
+		Temperature: c.Temperature,
+		TopK:        c.TopK,
+		TopP:        c.TopP,
+	}
+}
+
+func (c ChainConfig) PruneOptions() markov.PruneOptions {
+	return markov.PruneOptions{
+		MinOccurrences: c.PruneMinOccurrences,
+		MaxChildren:    c.PruneMaxChildren,
+		MaxStates:      c.PruneMaxStates,
+	}
+}
+
+func (c ChainConfig) ScoringOptions() handler.ScoringOptions {
+	return handler.ScoringOptions{
+		TargetPerplexity: c.TargetPerplexity,
+		LengthWeight:     c.LengthWeight,
+	}
+}
+
+// Returns the options to generate posts as configured.
+// Options given after them, e.g. the random source, are added by the caller.
+func (c ChainConfig) GenerateOptions() []handler.GeneratePostOption {
+	return []handler.GeneratePostOption{
+		handler.WithMinWordsCount(c.MinWordsCount),
+		handler.WithBackoff(c.BackoffOptions()),
+		handler.WithSampling(c.SamplingOptions()),
+		handler.WithCandidatesCount(c.CandidatesCount),
+		handler.WithScoring(c.ScoringOptions()),
+	}
+}
+
+func (c ChainConfig) DecayHalfLifeDuration() time.Duration {
+	return time.Duration(c.DecayHalfLife) * time.Second
+}
+
+func (c ChainConfig) ExpiresInDuration() time.Duration {
+	return time.Duration(c.ExpiresIn) * time.Second
+}
+
+func (c ChainConfig) ForgetDeletedIntervalDuration() time.Duration {
+	if c.ForgetDeletedInterval == 0 {
+		return time.Duration(c.ExpiresIn) * time.Second
+	}
+	return time.Duration(c.ForgetDeletedInterval) * time.Second
+}
+
+func (c ChainConfig) MinPostIntervalDuration() time.Duration {
+	return time.Duration(c.MinPostInterval) * time.Second
 }
@@ -3,6 +3,9 @@ package handler
 import (
 	"context"
 	"fmt"
+	"math"
+	"runtime"
+	"time"
 
 	"github.com/paralleltree/markov-bot-go/blog"
 	"github.com/paralleltree/markov-bot-go/lib"
@@ -14,6 +17,14 @@ import (
 type buildChainConf struct {
 	fetchStatusCount int
 	stateSize        int
+	tokenUnit        markov.TokenUnit
+	variableOrder    bool
+	concurrency      int
+	metadata         markov.Metadata
+	pruning          markov.PruneOptions
+	decayHalfLife    time.Duration
+	filter           func(blog.Post) bool
+	provenance       bool
 }
 
 func WithFetchStatusCount(fetchStatusCount int) func(c *buildChainConf) {
@@ -28,43 +39,160 @@ func WithStateSize(stateSize int) func(c *buildChainConf) {
 	}
 }
 
+func WithTokenUnit(unit markov.TokenUnit) func(c *buildChainConf) {
+	return func(c *buildChainConf) {
+		c.tokenUnit = unit
+	}
+}
+
+// Makes the chain store contexts of all orders up to the state size for backoff generation.
+func WithVariableOrder(variableOrder bool) func(c *buildChainConf) {
+	return func(c *buildChainConf) {
+		c.variableOrder = variableOrder
+	}
+}
+
+// Sets the number of workers analyzing statuses in parallel.
+// The built chain is the same regardless of the number.
+func WithConcurrency(concurrency int) func(c *buildChainConf) {
+	return func(c *buildChainConf) {
+		if concurrency > 0 {
+			c.concurrency = concurrency
+		}
+	}
+}
+
+// Sets the identity of the bot and the analyzer recorded in the model.
+// The build time and the numbers of statuses are filled on building.
+func WithMetadata(metadata markov.Metadata) func(c *buildChainConf) {
+	return func(c *buildChainConf) {
+		c.metadata = metadata
+	}
+}
+
+// Sets the predicate to choose statuses to learn. Statuses not satisfying it do not count toward the fetch count.
+// By default, private and direct statuses are skipped.
+func WithPostFilter(filter func(blog.Post) bool) func(c *buildChainConf) {
+	return func(c *buildChainConf) {
+		c.filter = filter
+	}
+}
+
+// Weighs each status by 0.5^(age/halfLife), where the age is measured from the build time,
+// so that recent statuses dominate the chain. Weights are floored at 1/1024 to keep every transition.
+// Statuses without the creation time are weighed as 1. Zero or less disables decaying.
+func WithDecayHalfLife(halfLife time.Duration) func(c *buildChainConf) {
+	return func(c *buildChainConf) {
+		c.decayHalfLife = halfLife
+	}
+}
+
+// Prunes the built chain to limit its size. See markov.PruneOptions for details.
+func WithPruning(opts markov.PruneOptions) func(c *buildChainConf) {
+	return func(c *buildChainConf) {
+		c.pruning = opts
+	}
+}
+
+// Records which status contributed each sentence, so that statuses can be forgotten without rebuilding.
+// Statuses without IDs are not recorded.
+func WithProvenance(provenance bool) func(c *buildChainConf) {
+	return func(c *buildChainConf) {
+		c.provenance = provenance
+	}
+}
+
 func BuildChain(ctx context.Context, client blog.BlogClient, analyzer morpheme.MorphemeAnalyzer, store persistence.PersistentStore, optFns ...func(*buildChainConf)) error {
 	conf := &buildChainConf{
 		fetchStatusCount: 100,
 		stateSize:        2,
+		tokenUnit:        markov.UnitWord,
+		concurrency:      runtime.NumCPU(),
+		filter:           blog.Post.IsPublic,
 	}
 	for _, f := range optFns {
 		f(conf)
 	}
 
-	chain := markov.NewChain(conf.stateSize)
-	iterator := lib.BuildIterator(client.GetPostsFetcher(ctx))
-
-	for i := 0; i < conf.fetchStatusCount; i++ {
-		status, hasNext, err := iterator()
+	chainOpts := []func(*markov.Chain){markov.WithUnit(conf.tokenUnit)}
+	if conf.variableOrder {
+		chainOpts = append(chainOpts, markov.WithVariableOrder())
+	}
+	chain := markov.NewChain(conf.stateSize, chainOpts...)
+	metadata := conf.metadata
+	if namer, ok := client.(blog.AccountNamer); ok && metadata.SourceAccount == "" {
+		account, err := namer.AccountName(ctx)
 		if err != nil {
-			return fmt.Errorf("fetch statuses: %w", err)
-		}
-		if !hasNext {
-			break
+			return fmt.Errorf("get source account: %w", err)
 		}
+		metadata.SourceAccount = account
+	}
+	// counted on the fetching goroutine, and read after all statuses are consumed
+	skippedCount := 0
+	newIterator := func(ctx context.Context) lib.IteratorFunc[blog.Post] {
+		return lib.FilterIterator(lib.BuildIterator(client.GetPostsFetcher(ctx)), func(status blog.Post) bool {
+			if conf.filter(status) {
+				return true
+			}
+			skippedCount++
+			return false
+		})
+	}
 
-		result, err := analyzer.Analyze(status)
-		if err != nil {
-			return fmt.Errorf("analyze text: %w", err)
+	now := time.Now()
+	err := analyzeStatuses(ctx, newIterator, analyzer, conf.fetchStatusCount, conf.concurrency, func(status blog.Post, sentences [][]string) {
+		metadata.FetchedStatusesCount++
+		// sources return statuses from the newest one
+		if metadata.NewestStatusID == "" {
+			metadata.NewestStatusID = status.ID
 		}
-		for _, v := range result {
-			chain.AddSource(v)
+		if status.ID != "" {
+			metadata.OldestStatusID = status.ID
 		}
-	}
-
-	dump, err := chain.Dump()
+		weight := decayWeight(now, status.CreatedAt, conf.decayHalfLife)
+		kept := false
+		for _, v := range sentences {
+			if conf.provenance && status.ID != "" {
+				chain.AddAttributedSource(status.ID, v, weight)
+			} else {
+				chain.AddWeightedSource(v, weight)
+			}
+			kept = kept || len(v) > 0
+		}
+		if kept {
+			metadata.KeptStatusesCount++
+		}
+	})
 	if err != nil {
-		return fmt.Errorf("dump chain: %w", err)
+		return err
+	}
+	metadata.FetchedStatusesCount += skippedCount
+	if conf.pruning != (markov.PruneOptions{}) {
+		stats := chain.Prune(conf.pruning)
+		metadata.Pruning = &stats
 	}
-	if err := store.Save(ctx, dump); err != nil {
-		return fmt.Errorf("save chain: %w", err)
+	metadata.BuiltAt = now
+	chain.Metadata = &metadata
+
+	if err := saveModel(ctx, chain, store); err != nil {
+		return err
 	}
 
 	return nil
 }
+
+// The weight of statuses older than about 10 half-lives.
+// Keeps every transition positive, since zero weights break sampling and backoff.
+const minDecayWeight = 1.0 / 1024
+
+// Returns the weight of a status created at createdAt, which halves every halfLife before now.
+func decayWeight(now, createdAt time.Time, halfLife time.Duration) float64 {
+	if halfLife <= 0 || createdAt.IsZero() {
+		return 1
+	}
+	age := now.Sub(createdAt)
+	if age < 0 {
+		age = 0
+	}
+	return max(math.Pow(0.5, float64(age)/float64(halfLife)), minDecayWeight)
+}
@@ -0,0 +1,429 @@
+package handler_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paralleltree/markov-bot-go/blog"
+	"github.com/paralleltree/markov-bot-go/handler"
+	"github.com/paralleltree/markov-bot-go/lib"
+	"github.com/paralleltree/markov-bot-go/markov"
+	"github.com/paralleltree/markov-bot-go/persistence"
+)
+
+func TestBuildChain_BuildsSameChainRegardlessOfConcurrency(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	statuses := []string{}
+	for i := 0; i < 50; i++ {
+		statuses = append(statuses, strings.Repeat("あいうえお", i%5+1)+strings.Repeat("かきくけこ", i%3+1))
+	}
+	build := func(concurrency int) []byte {
+		store := persistence.NewMemoryStore()
+		fetchClient := blog.NewRecordableBlogClient(statuses)
+		if err := handler.BuildChain(ctx, fetchClient, &slowAnalyzer{}, store, handler.WithConcurrency(concurrency), handler.WithFetchStatusCount(len(statuses))); err != nil {
+			t.Fatalf("BuildChain() should not return error, but got: %v", err)
+		}
+		data, err := store.Load(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error while loading chain: %v", err)
+		}
+		// ignore the build time
+		chain, err := markov.LoadChain(data)
+		if err != nil {
+			t.Fatalf("unexpected error while loading chain: %v", err)
+		}
+		chain.Metadata = nil
+		data, err = chain.Dump()
+		if err != nil {
+			t.Fatalf("unexpected error while dumping chain: %v", err)
+		}
+		return data
+	}
+
+	// act
+	want := build(1)
+	got := build(8)
+
+	// assert
+	if string(want) != string(got) {
+		t.Fatalf("chains should be same: want %s, but got %s", want, got)
+	}
+}
+
+func TestBuildChain_WhenAnalyzerFails_ReturnsError(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	fetchClient := blog.NewRecordableBlogClient([]string{"a", "b", "c", "d"})
+	store := persistence.NewMemoryStore()
+
+	// act
+	err := handler.BuildChain(ctx, fetchClient, &failingAnalyzer{}, store, handler.WithConcurrency(2))
+
+	// assert
+	if !errors.Is(err, errAnalyzeFailed) {
+		t.Fatalf("BuildChain() should return analyzer error, but got: %v", err)
+	}
+	if _, ok, _ := store.ModTime(ctx); ok {
+		t.Fatalf("chain should not be saved")
+	}
+}
+
+// records the context given to the fetcher
+type contextRecordingBlogClient struct {
+	blog.BlogClient
+	ctx context.Context
+}
+
+func (c *contextRecordingBlogClient) GetPostsFetcher(ctx context.Context) lib.ChunkIteratorFunc[blog.Post] {
+	c.ctx = ctx
+	return c.BlogClient.GetPostsFetcher(ctx)
+}
+
+func TestBuildChain_WhenAnalyzerFails_CancelsFetching(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	fetchClient := &contextRecordingBlogClient{BlogClient: blog.NewRecordableBlogClient([]string{"a", "b", "c", "d"})}
+	store := persistence.NewMemoryStore()
+
+	// act
+	err := handler.BuildChain(ctx, fetchClient, &failingAnalyzer{}, store, handler.WithConcurrency(2))
+
+	// assert
+	if !errors.Is(err, errAnalyzeFailed) {
+		t.Fatalf("BuildChain() should return analyzer error, but got: %v", err)
+	}
+	if fetchClient.ctx == nil {
+		t.Fatalf("posts should be fetched")
+	}
+	select {
+	case <-fetchClient.ctx.Done():
+	default:
+		t.Fatalf("the context of fetching should be done")
+	}
+}
+
+func TestBuildChain_WhenContextIsCanceled_ReturnsError(t *testing.T) {
+	// arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	fetchClient := blog.NewRecordableBlogClient([]string{"a", "b", "c", "d"})
+	store := persistence.NewMemoryStore()
+
+	// act
+	err := handler.BuildChain(ctx, fetchClient, &slowAnalyzer{}, store, handler.WithConcurrency(2))
+
+	// assert
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("BuildChain() should return context.Canceled, but got: %v", err)
+	}
+}
+
+func TestBuildChain_RecordsMetadata(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	fetchClient := blog.NewRecordableBlogClient([]string{"abc", "", "de"})
+	store := persistence.NewMemoryStore()
+	before := time.Now()
+
+	// act
+	err := handler.BuildChain(ctx, fetchClient, &slowAnalyzer{}, store,
+		handler.WithMetadata(markov.Metadata{BotName: "bot", Analyzer: "test"}))
+
+	// assert
+	if err != nil {
+		t.Fatalf("BuildChain() should not return error, but got: %v", err)
+	}
+	data, err := store.Load(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error while loading chain: %v", err)
+	}
+	got, err := markov.LoadMetadata(data)
+	if err != nil {
+		t.Fatalf("unexpected error while loading metadata: %v", err)
+	}
+	if got == nil {
+		t.Fatalf("metadata should be recorded")
+	}
+	if got.BuiltAt.Before(before) {
+		t.Errorf("unexpected build time: %v", got.BuiltAt)
+	}
+	want := markov.Metadata{
+		BuiltAt:              got.BuiltAt,
+		BotName:              "bot",
+		FetchedStatusesCount: 3,
+		KeptStatusesCount:    2,
+		Analyzer:             "test",
+	}
+	if !reflect.DeepEqual(want, *got) {
+		t.Errorf("unexpected metadata: want %+v, but got %+v", want, *got)
+	}
+}
+
+// names the account posts are fetched from
+type namedBlogClient struct {
+	blog.BlogClient
+	name string
+}
+
+func (c *namedBlogClient) AccountName(ctx context.Context) (string, error) {
+	return c.name, nil
+}
+
+func TestBuildChain_RecordsSourceAccountOfClient(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	fetchClient := &namedBlogClient{BlogClient: blog.NewRecordableBlogClient([]string{"abc"}), name: "@bot@example.com"}
+	store := persistence.NewMemoryStore()
+
+	// act
+	err := handler.BuildChain(ctx, fetchClient, &slowAnalyzer{}, store,
+		handler.WithMetadata(markov.Metadata{SourcePlatform: "mastodon"}))
+
+	// assert
+	if err != nil {
+		t.Fatalf("BuildChain() should not return error, but got: %v", err)
+	}
+	data, err := store.Load(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error while loading chain: %v", err)
+	}
+	got, err := markov.LoadMetadata(data)
+	if err != nil {
+		t.Fatalf("unexpected error while loading metadata: %v", err)
+	}
+	if got.SourceAccount != "@bot@example.com" {
+		t.Fatalf("unexpected source account: %q", got.SourceAccount)
+	}
+}
+
+func TestBuildChain_SkipsPrivateStatusesAndRecordsIDs(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	fetchClient := blog.NewRecordableBlogClientWithPosts([]blog.Post{
+		{ID: "5", Body: "dm", Visibility: blog.VisibilityDirect},
+		{ID: "4", Body: "ab", Visibility: blog.VisibilityPublic},
+		{ID: "3", Body: "private", Visibility: blog.VisibilityPrivate},
+		{ID: "2", Body: "cd", Visibility: blog.VisibilityUnlisted},
+		{ID: "1", Body: "ef", Visibility: blog.VisibilityPublic},
+	})
+	store := persistence.NewMemoryStore()
+
+	// act
+	err := handler.BuildChain(ctx, fetchClient, &slowAnalyzer{}, store, handler.WithStateSize(1), handler.WithFetchStatusCount(2))
+
+	// assert
+	if err != nil {
+		t.Fatalf("BuildChain() should not return error, but got: %v", err)
+	}
+	data, err := store.Load(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error while loading chain: %v", err)
+	}
+	chain, err := markov.LoadChain(data)
+	if err != nil {
+		t.Fatalf("unexpected error while loading chain: %v", err)
+	}
+	if got := chain.Stats(10).TopSentenceStarters; len(got) != 2 || got[0].Token != "a" || got[1].Token != "c" {
+		t.Errorf("only public statuses should be learned, but got starters: %+v", got)
+	}
+	m := chain.Metadata
+	if m.FetchedStatusesCount != 4 || m.KeptStatusesCount != 2 {
+		t.Errorf("unexpected statuses count: fetched %d, kept %d", m.FetchedStatusesCount, m.KeptStatusesCount)
+	}
+	if m.NewestStatusID != "4" || m.OldestStatusID != "2" {
+		t.Errorf("unexpected status ids: newest %s, oldest %s", m.NewestStatusID, m.OldestStatusID)
+	}
+}
+
+func TestBuildChain_WithPruning_RecordsPruneStats(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	fetchClient := blog.NewRecordableBlogClient([]string{"abc", "abc", "abd"})
+	store := persistence.NewMemoryStore()
+	opts := markov.PruneOptions{MinOccurrences: 2}
+
+	// act
+	err := handler.BuildChain(ctx, fetchClient, &slowAnalyzer{}, store, handler.WithStateSize(1), handler.WithPruning(opts))
+
+	// assert
+	if err != nil {
+		t.Fatalf("BuildChain() should not return error, but got: %v", err)
+	}
+	data, err := store.Load(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error while loading chain: %v", err)
+	}
+	chain, err := markov.LoadChain(data)
+	if err != nil {
+		t.Fatalf("unexpected error while loading chain: %v", err)
+	}
+	want := &markov.PruneStats{
+		Options:           opts,
+		StatesBefore:      5,
+		StatesAfter:       4,
+		TransitionsBefore: 6,
+		TransitionsAfter:  4,
+	}
+	if !reflect.DeepEqual(want, chain.Metadata.Pruning) {
+		t.Fatalf("unexpected prune stats: want %+v, but got %+v", want, chain.Metadata.Pruning)
+	}
+}
+
+func TestBuildChain_WithDecayHalfLife_WeighsRecentStatuses(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	halfLife := 24 * time.Hour
+	fetchClient := blog.NewRecordableBlogClientWithPosts([]blog.Post{
+		{Body: "ab", CreatedAt: time.Now()},
+		{Body: "xy", CreatedAt: time.Now().Add(-2 * halfLife)},
+		{Body: "pq"},
+	})
+	store := persistence.NewMemoryStore()
+
+	// act
+	err := handler.BuildChain(ctx, fetchClient, &slowAnalyzer{}, store, handler.WithStateSize(1), handler.WithDecayHalfLife(halfLife))
+
+	// assert
+	if err != nil {
+		t.Fatalf("BuildChain() should not return error, but got: %v", err)
+	}
+	data, err := store.Load(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error while loading chain: %v", err)
+	}
+	chain, err := markov.LoadChain(data)
+	if err != nil {
+		t.Fatalf("unexpected error while loading chain: %v", err)
+	}
+	want := map[string]float64{"a": 1, "x": 0.25, "p": 1}
+	for _, v := range chain.Stats(3).TopSentenceStarters {
+		if math.Abs(want[v.Token]-v.Occurrences) > 1e-3 {
+			t.Errorf("unexpected weight of %s: want %v, but got %v", v.Token, want[v.Token], v.Occurrences)
+		}
+	}
+}
+
+func TestBuildChain_WithDecayHalfLife_MeasuresAgeFromBuildTime(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	halfLife := time.Hour
+	newest := time.Now().Add(-halfLife)
+	fetchClient := blog.NewRecordableBlogClientWithPosts([]blog.Post{
+		{Body: "ab", CreatedAt: newest},
+		{Body: "xy", CreatedAt: newest.Add(-halfLife)},
+		{Body: "pq", CreatedAt: newest.Add(-10000 * halfLife)},
+	})
+	store := persistence.NewMemoryStore()
+
+	// act
+	err := handler.BuildChain(ctx, fetchClient, &slowAnalyzer{}, store, handler.WithStateSize(2), handler.WithVariableOrder(true), handler.WithDecayHalfLife(halfLife))
+
+	// assert
+	if err != nil {
+		t.Fatalf("BuildChain() should not return error, but got: %v", err)
+	}
+	data, err := store.Load(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error while loading chain: %v", err)
+	}
+	chain, err := markov.LoadChain(data)
+	if err != nil {
+		t.Fatalf("unexpected error while loading chain: %v", err)
+	}
+	want := map[string]float64{"a": 0.5, "x": 0.25, "p": 1.0 / 1024}
+	got := map[string]float64{}
+	for _, v := range chain.Stats(3).TopSentenceStarters {
+		got[v.Token] = v.Occurrences
+	}
+	for token, w := range want {
+		if math.Abs(w-got[token]) > 1e-3 {
+			t.Errorf("unexpected weight of %s: want %v, but got %v", token, w, got[token])
+		}
+	}
+	// sampling stays well-defined with the weight of very old statuses
+	for i := 0; i < 20; i++ {
+		if result := chain.Generate(); len(result) == 0 {
+			t.Fatalf("Generate() should return tokens")
+		}
+	}
+}
+
+func TestModelBuiltAt(t *testing.T) {
+	builtAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	withMetadata := markov.NewChain(1)
+	withMetadata.Metadata = &markov.Metadata{BuiltAt: builtAt}
+	withoutMetadata := markov.NewChain(1)
+
+	cases := []struct {
+		name         string
+		chain        *markov.Chain
+		wantExists   bool
+		wantRecorded bool
+	}{
+		{name: "model not exists", chain: nil, wantExists: false},
+		{name: "recorded build time is used", chain: withMetadata, wantExists: true, wantRecorded: true},
+		{name: "falls back to modtime", chain: withoutMetadata, wantExists: true, wantRecorded: false},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			ctx := context.Background()
+			store := persistence.NewMemoryStore()
+			if tt.chain != nil {
+				data, err := tt.chain.Dump()
+				if err != nil {
+					t.Fatalf("unexpected error while dumping chain: %v", err)
+				}
+				if err := store.Save(ctx, data); err != nil {
+					t.Fatalf("unexpected error while saving chain: %v", err)
+				}
+			}
+			modTime, _, _ := store.ModTime(ctx)
+
+			// act
+			got, ok, err := handler.ModelBuiltAt(ctx, store)
+
+			// assert
+			if err != nil {
+				t.Fatalf("ModelBuiltAt() should not return error, but got: %v", err)
+			}
+			if tt.wantExists != ok {
+				t.Fatalf("unexpected existence: want %v, but got %v", tt.wantExists, ok)
+			}
+			if !ok {
+				return
+			}
+			want := modTime
+			if tt.wantRecorded {
+				want = builtAt
+			}
+			if !want.Equal(got) {
+				t.Errorf("unexpected build time: want %v, but got %v", want, got)
+			}
+		})
+	}
+}
+
+// Splits texts into characters after random delays, so that results arrive out of order.
+type slowAnalyzer struct{}
+
+func (a *slowAnalyzer) Analyze(text string) ([][]string, error) {
+	time.Sleep(time.Duration(rand.Intn(1000)) * time.Microsecond)
+	return [][]string{strings.Split(text, "")}, nil
+}
+
+var errAnalyzeFailed = errors.New("analyze failed")
+
+type failingAnalyzer struct{}
+
+func (a *failingAnalyzer) Analyze(text string) ([][]string, error) {
+	return nil, errAnalyzeFailed
+}
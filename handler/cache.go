@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/paralleltree/markov-bot-go/markov"
+	"github.com/paralleltree/markov-bot-go/persistence"
+)
+
+// ModelCache keeps a model decoded in memory across runs in the same process, e.g. warm Lambda containers,
+// and reloads it only if the store reports that the model has been modified.
+// Models in stores not implementing persistence.ConditionalLoader are loaded every time.
+// The cached model is tied to the store it was loaded from, so stores to be cached across runs must be reused,
+// since another store may give the same ETag to a different model.
+type ModelCache struct {
+	mu       sync.Mutex
+	store    persistence.PersistentStore
+	etag     string
+	model    *markov.FrozenChain
+	metadata *markov.Metadata
+}
+
+func NewModelCache() *ModelCache {
+	return &ModelCache{}
+}
+
+// Returns the time the model in the store was built like ModelBuiltAt, using the cached model if not modified.
+// The model is loaded into the cache to be reused for generation.
+// For stores not implementing persistence.ConditionalLoader, only the metadata is read as ModelBuiltAt does,
+// since the model would be loaded again for generation anyway.
+func (c *ModelCache) ModelBuiltAt(ctx context.Context, store persistence.PersistentStore) (time.Time, bool, error) {
+	if _, ok := store.(persistence.ConditionalLoader); !ok {
+		return ModelBuiltAt(ctx, store)
+	}
+	return modelBuiltAt(ctx, store, func() (*markov.Metadata, error) {
+		_, metadata, err := c.load(ctx, store)
+		return metadata, err
+	})
+}
+
+func (c *ModelCache) load(ctx context.Context, store persistence.PersistentStore) (*markov.FrozenChain, *markov.Metadata, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	loader, ok := store.(persistence.ConditionalLoader)
+	if !ok {
+		chain, err := loadModel(ctx, store)
+		if err != nil {
+			return nil, nil, err
+		}
+		return chain.Freeze(), chain.Metadata, nil
+	}
+
+	etag := ""
+	if c.store == store {
+		etag = c.etag
+	}
+	r, etag, modified, err := loader.OpenIfModified(ctx, etag)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load chain data: %w", err)
+	}
+	if !modified {
+		return c.model, c.metadata, nil
+	}
+	defer r.Close()
+
+	chain, err := markov.ReadChain(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reconstruct chain: %w", err)
+	}
+	c.store, c.etag, c.model, c.metadata = store, etag, chain.Freeze(), chain.Metadata
+	return c.model, c.metadata, nil
+}
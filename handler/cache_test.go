@@ -0,0 +1,144 @@
+package handler_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/paralleltree/markov-bot-go/blog"
+	"github.com/paralleltree/markov-bot-go/handler"
+	"github.com/paralleltree/markov-bot-go/markov"
+	"github.com/paralleltree/markov-bot-go/morpheme"
+	"github.com/paralleltree/markov-bot-go/persistence"
+)
+
+// counts the times the model is actually downloaded
+type downloadCountingStore struct {
+	persistence.PersistentStore
+	downloads int
+}
+
+func (s *downloadCountingStore) OpenIfModified(ctx context.Context, etag string) (io.ReadCloser, string, bool, error) {
+	r, newETag, modified, err := s.PersistentStore.(persistence.ConditionalLoader).OpenIfModified(ctx, etag)
+	if modified {
+		s.downloads++
+	}
+	return r, newETag, modified, err
+}
+
+func TestModelCache_LoadsModelOnlyWhenModified(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	inputText := "家族でお出かけ"
+	postClient := blog.NewRecordableBlogClient(nil)
+	store := &downloadCountingStore{PersistentStore: persistence.NewMemoryStore()}
+	cache := handler.NewModelCache()
+	build := func() {
+		fetchClient := blog.NewRecordableBlogClient([]string{inputText})
+		if err := handler.BuildChain(ctx, fetchClient, morpheme.NewGraphemeAnalyzer(), store, handler.WithTokenUnit(markov.UnitCharacter)); err != nil {
+			t.Fatalf("BuildChain() should not return error, but got: %v", err)
+		}
+	}
+	build()
+
+	// act
+	for i := 0; i < 2; i++ {
+		if _, _, err := cache.ModelBuiltAt(ctx, store); err != nil {
+			t.Fatalf("ModelBuiltAt() should not return error, but got: %v", err)
+		}
+		if err := handler.GenerateAndPost(ctx, postClient, store, handler.WithModelCache(cache)); err != nil {
+			t.Fatalf("GenerateAndPost() should not return error, but got: %v", err)
+		}
+	}
+	downloadsBeforeRebuild := store.downloads
+	build()
+	if err := handler.GenerateAndPost(ctx, postClient, store, handler.WithModelCache(cache)); err != nil {
+		t.Fatalf("GenerateAndPost() should not return error, but got: %v", err)
+	}
+
+	// assert
+	if downloadsBeforeRebuild != 1 {
+		t.Errorf("unexpected downloads before rebuild: want %d, but got %d", 1, downloadsBeforeRebuild)
+	}
+	if store.downloads != 2 {
+		t.Errorf("unexpected downloads after rebuild: want %d, but got %d", 2, store.downloads)
+	}
+	for _, content := range postClient.PostedContents {
+		if inputText != content {
+			t.Errorf("unexpected output: want %s, but got %s", inputText, content)
+		}
+	}
+}
+
+func TestModelCache_WithStoreNotSupportingConditionalLoad_ReturnsBuildTime(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	fetchClient := blog.NewRecordableBlogClient([]string{"おでかけ"})
+	store := struct{ persistence.PersistentStore }{persistence.NewMemoryStore()}
+	if err := handler.BuildChain(ctx, fetchClient, morpheme.NewGraphemeAnalyzer(), store, handler.WithTokenUnit(markov.UnitCharacter)); err != nil {
+		t.Fatalf("BuildChain() should not return error, but got: %v", err)
+	}
+	want, _, err := handler.ModelBuiltAt(ctx, store)
+	if err != nil {
+		t.Fatalf("ModelBuiltAt() should not return error, but got: %v", err)
+	}
+
+	// act
+	got, ok, err := handler.NewModelCache().ModelBuiltAt(ctx, store)
+
+	// assert
+	if err != nil {
+		t.Fatalf("ModelBuiltAt() should not return error, but got: %v", err)
+	}
+	if !ok {
+		t.Fatalf("ModelBuiltAt() should report the model exists")
+	}
+	if !want.Equal(got) {
+		t.Errorf("unexpected build time: want %v, but got %v", want, got)
+	}
+}
+
+// gives the same ETag to any data, like stores in different buckets can
+type constantETagStore struct {
+	persistence.PersistentStore
+}
+
+func (s *constantETagStore) OpenIfModified(ctx context.Context, etag string) (io.ReadCloser, string, bool, error) {
+	const constantETag = "constant"
+	if etag == constantETag {
+		return nil, etag, false, nil
+	}
+	r, err := persistence.OpenStream(ctx, s.PersistentStore)
+	return r, constantETag, true, err
+}
+
+func TestModelCache_WithStoresGivingSameETag_LoadsModelOfEachStore(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	newStore := func(inputText string) persistence.PersistentStore {
+		store := &constantETagStore{PersistentStore: persistence.NewMemoryStore()}
+		fetchClient := blog.NewRecordableBlogClient([]string{inputText})
+		if err := handler.BuildChain(ctx, fetchClient, morpheme.NewGraphemeAnalyzer(), store, handler.WithTokenUnit(markov.UnitCharacter)); err != nil {
+			t.Fatalf("BuildChain() should not return error, but got: %v", err)
+		}
+		return store
+	}
+	storeA := newStore("りんご")
+	storeB := newStore("バナナ")
+	postClient := blog.NewRecordableBlogClient(nil)
+	cache := handler.NewModelCache()
+	if _, _, err := cache.ModelBuiltAt(ctx, storeA); err != nil {
+		t.Fatalf("ModelBuiltAt() should not return error, but got: %v", err)
+	}
+
+	// act
+	err := handler.GenerateAndPost(ctx, postClient, storeB, handler.WithModelCache(cache))
+
+	// assert
+	if err != nil {
+		t.Fatalf("GenerateAndPost() should not return error, but got: %v", err)
+	}
+	if len(postClient.PostedContents) != 1 || postClient.PostedContents[0] != "バナナ" {
+		t.Errorf("unexpected output: want %s, but got %v", "バナナ", postClient.PostedContents)
+	}
+}
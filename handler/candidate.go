@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/paralleltree/markov-bot-go/markov"
+)
+
+// Candidate is a generated text with its score.
+type Candidate struct {
+	Text       string
+	WordsCount int
+	Perplexity float64
+	Score      float64
+}
+
+// Options to rank candidates. The candidate with the highest score is posted.
+type ScoringOptions struct {
+	// Candidates with perplexity closer to this value get higher scores:
+	// lower perplexity means the text is copied from the source, and higher means it is random.
+	// Zero disables this term.
+	TargetPerplexity float64
+	// Weight of log(1 + words count) added to the score. Positive values prefer longer texts.
+	LengthWeight float64
+}
+
+func (o ScoringOptions) score(perplexity float64, wordsCount int) float64 {
+	score := o.LengthWeight * math.Log(1+float64(wordsCount))
+	if 0 < o.TargetPerplexity {
+		score -= math.Abs(math.Log(perplexity / o.TargetPerplexity))
+	}
+	return score
+}
+
+func newCandidate(model *markov.FrozenChain, tokens []string, opts ScoringOptions) Candidate {
+	perplexity := markov.Perplexity(model.Surprisals(tokens))
+	return Candidate{
+		Text:       model.Join(tokens),
+		WordsCount: len(tokens),
+		Perplexity: perplexity,
+		Score:      opts.score(perplexity, len(tokens)),
+	}
+}
+
+// Returns the index of the candidate with the highest score. The earliest one wins on ties.
+func bestCandidate(candidates []Candidate) int {
+	best := 0
+	for i, c := range candidates {
+		if candidates[best].Score < c.Score {
+			best = i
+		}
+	}
+	return best
+}
+
+func writeCandidates(w io.Writer, candidates []Candidate, chosen int) error {
+	for i, c := range candidates {
+		mark := " "
+		if i == chosen {
+			mark = "*"
+		}
+		if _, err := fmt.Fprintf(w, "%s score=%.3f perplexity=%.3f words=%d\t%s\n", mark, c.Score, c.Perplexity, c.WordsCount, c.Text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/paralleltree/markov-bot-go/markov"
+	"github.com/paralleltree/markov-bot-go/persistence"
+)
+
+type ExportFormat string
+
+const (
+	ExportFormatTSV  ExportFormat = "tsv"
+	ExportFormatDOT  ExportFormat = "dot"
+	ExportFormatJSON ExportFormat = "json"
+)
+
+type exportConf struct {
+	format ExportFormat
+	token  string
+	depth  int
+}
+
+func WithExportFormat(format ExportFormat) func(c *exportConf) {
+	return func(c *exportConf) {
+		c.format = format
+	}
+}
+
+// Sets the token at the center of the DOT graph and the number of transitions to include around it.
+func WithNeighborhood(token string, depth int) func(c *exportConf) {
+	return func(c *exportConf) {
+		c.token = token
+		c.depth = depth
+	}
+}
+
+// Writes the saved model in a human-readable format.
+func ExportModel(ctx context.Context, store persistence.PersistentStore, w io.Writer, optFns ...func(*exportConf)) error {
+	conf := &exportConf{
+		format: ExportFormatTSV,
+		depth:  1,
+	}
+	for _, f := range optFns {
+		f(conf)
+	}
+	if conf.format == ExportFormatDOT && conf.token == "" {
+		return fmt.Errorf("token is required for %s format", conf.format)
+	}
+
+	chain, err := loadModel(ctx, store)
+	if err != nil {
+		return fmt.Errorf("load model: %w", err)
+	}
+
+	switch conf.format {
+	case ExportFormatTSV:
+		err = chain.WriteTSV(w)
+	case ExportFormatDOT:
+		err = chain.WriteDOT(w, conf.token, conf.depth)
+	case ExportFormatJSON:
+		err = chain.WriteJSON(w)
+	default:
+		return fmt.Errorf("unsupported format: %s", conf.format)
+	}
+	if err != nil {
+		return fmt.Errorf("write %s: %w", conf.format, err)
+	}
+	return nil
+}
+
+// Reads a model exported in TSV format and saves it.
+func ImportModel(ctx context.Context, r io.Reader, store persistence.PersistentStore) error {
+	chain, err := markov.ReadTSV(r)
+	if err != nil {
+		return fmt.Errorf("read tsv: %w", err)
+	}
+	if err := saveModel(ctx, chain, store); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/paralleltree/markov-bot-go/handler"
+	"github.com/paralleltree/markov-bot-go/markov"
+	"github.com/paralleltree/markov-bot-go/persistence"
+)
+
+func TestImportModel_RestoresExportedModel(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	chain := markov.NewChain(2)
+	chain.AddSource([]string{"a ", "b ", "c"})
+	chain.AddSource([]string{"a ", "d"})
+	want, err := chain.Dump()
+	if err != nil {
+		t.Fatalf("unexpected error while dumping chain: %v", err)
+	}
+	src := persistence.NewMemoryStore()
+	if err := src.Save(ctx, want); err != nil {
+		t.Fatalf("unexpected error while saving chain: %v", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := handler.ExportModel(ctx, src, buf, handler.WithExportFormat(handler.ExportFormatTSV)); err != nil {
+		t.Fatalf("ExportModel() should not return error, but got: %v", err)
+	}
+	dst := persistence.NewMemoryStore()
+
+	// act
+	err = handler.ImportModel(ctx, buf, dst)
+
+	// assert
+	if err != nil {
+		t.Fatalf("ImportModel() should not return error, but got: %v", err)
+	}
+	got, err := dst.Load(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error while loading chain: %v", err)
+	}
+	if string(want) != string(got) {
+		t.Fatalf("unexpected model: want %s, but got %s", want, got)
+	}
+}
+
+func TestExportModel_WithDOTFormatWithoutToken_ReturnsError(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	store := persistence.NewMemoryStore()
+
+	// act
+	err := handler.ExportModel(ctx, store, &bytes.Buffer{}, handler.WithExportFormat(handler.ExportFormatDOT))
+
+	// assert
+	if err == nil {
+		t.Fatalf("ExportModel() should return error, but got nil")
+	}
+}
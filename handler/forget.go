@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/paralleltree/markov-bot-go/blog"
+	"github.com/paralleltree/markov-bot-go/lib"
+	"github.com/paralleltree/markov-bot-go/markov"
+	"github.com/paralleltree/markov-bot-go/persistence"
+)
+
+// Removes contributions of the posts from the saved model, which must be built with provenance.
+// Returns IDs of the forgotten posts. Posts not recorded in the model are ignored.
+func ForgetPosts(ctx context.Context, store persistence.PersistentStore, ids []string) ([]string, error) {
+	chain, err := loadModel(ctx, store)
+	if err != nil {
+		return nil, fmt.Errorf("load model: %w", err)
+	}
+	return forgetPosts(ctx, chain, store, ids)
+}
+
+func forgetPosts(ctx context.Context, chain *markov.Chain, store persistence.PersistentStore, ids []string) ([]string, error) {
+	forgotten := []string{}
+	for _, id := range ids {
+		if chain.Forget(id) {
+			forgotten = append(forgotten, id)
+		}
+	}
+	if len(forgotten) == 0 {
+		return forgotten, nil
+	}
+
+	if err := saveModel(ctx, chain, store); err != nil {
+		return nil, err
+	}
+	return forgotten, nil
+}
+
+// Forgets posts recorded in the saved model which no longer exist on the source.
+// Posts are fetched from the newest one until the oldest recorded post,
+// and recorded posts missing among them are regarded as deleted.
+// Status IDs must be numeric and increase over time like Mastodon's, so this only supports Mastodon sources;
+// with other IDs, where fetching stops is undefined and posts which still exist can be forgotten.
+// Returns IDs of the forgotten posts.
+func ForgetDeletedPosts(ctx context.Context, client blog.BlogClient, store persistence.PersistentStore) ([]string, error) {
+	chain, err := loadModel(ctx, store)
+	if err != nil {
+		return nil, fmt.Errorf("load model: %w", err)
+	}
+	ids := chain.SourceIDs()
+	if len(ids) == 0 {
+		return []string{}, nil
+	}
+	oldest := ids[0]
+	for _, id := range ids[1:] {
+		if compareStatusIDs(id, oldest) < 0 {
+			oldest = id
+		}
+	}
+
+	existing := map[string]bool{}
+	iterator := lib.BuildIterator(client.GetPostsFetcher(ctx))
+	for {
+		post, ok, err := iterator()
+		if err != nil {
+			return nil, fmt.Errorf("fetch posts: %w", err)
+		}
+		if !ok {
+			break
+		}
+		existing[post.ID] = true
+		if post.ID != "" && compareStatusIDs(post.ID, oldest) <= 0 {
+			break
+		}
+	}
+
+	deleted := []string{}
+	for _, id := range ids {
+		if !existing[id] {
+			deleted = append(deleted, id)
+		}
+	}
+	return forgetPosts(ctx, chain, store, deleted)
+}
+
+// Forgets deleted posts like ForgetDeletedPosts unless they were checked within the interval.
+// The time of the last check is recorded in checkedStore, since fetching posts back to the oldest recorded one is costly.
+// Returns IDs of the forgotten posts, which are nil if the check is skipped.
+func ForgetDeletedPostsEvery(ctx context.Context, client blog.BlogClient, store persistence.PersistentStore, checkedStore persistence.PersistentStore, interval time.Duration) ([]string, error) {
+	checked, err := recordedWithin(ctx, checkedStore, interval, "last check")
+	if err != nil {
+		return nil, err
+	}
+	if checked {
+		return nil, nil
+	}
+	now := time.Now()
+	forgotten, err := ForgetDeletedPosts(ctx, client, store)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordTime(ctx, checkedStore, now, "last check"); err != nil {
+		return nil, err
+	}
+	return forgotten, nil
+}
+
+// Compares status IDs as numbers like Mastodon's, which increase over time.
+// IDs must be decimal numbers without leading zeros, and later statuses must have larger IDs.
+func compareStatusIDs(a, b string) int {
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,147 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paralleltree/markov-bot-go/blog"
+	"github.com/paralleltree/markov-bot-go/handler"
+	"github.com/paralleltree/markov-bot-go/persistence"
+)
+
+func TestForgetPosts_RemovesContributionsOfPosts(t *testing.T) {
+	cases := []struct {
+		name          string
+		ids           []string
+		wantForgotten []string
+		wantPosts     []blog.Post
+	}{
+		{
+			name:          "recorded post",
+			ids:           []string{"102"},
+			wantForgotten: []string{"102"},
+			wantPosts:     []blog.Post{{ID: "103", Body: "あいう"}, {ID: "101", Body: "あえお"}},
+		},
+		{
+			name:          "unknown post",
+			ids:           []string{"999"},
+			wantForgotten: []string{},
+			wantPosts:     provenanceTestPosts,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			ctx := context.Background()
+			store := buildProvenanceModel(t, provenanceTestPosts)
+
+			// act
+			forgotten, err := handler.ForgetPosts(ctx, store, tt.ids)
+
+			// assert
+			if err != nil {
+				t.Fatalf("ForgetPosts() should not return error, but got: %v", err)
+			}
+			if !reflect.DeepEqual(tt.wantForgotten, forgotten) {
+				t.Fatalf("unexpected forgotten posts: want %v, but got %v", tt.wantForgotten, forgotten)
+			}
+			want := exportTransitions(t, buildProvenanceModel(t, tt.wantPosts))
+			if got := exportTransitions(t, store); want != got {
+				t.Fatalf("unexpected model: want\n%s\nbut got\n%s", want, got)
+			}
+		})
+	}
+}
+
+func TestForgetDeletedPosts_ForgetsPostsMissingOnSource(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	store := buildProvenanceModel(t, provenanceTestPosts)
+	// the newer post is not recorded in the model, and 102 has been deleted
+	client := blog.NewRecordableBlogClientWithPosts([]blog.Post{
+		{ID: "104", Body: "かきく"},
+		{ID: "103", Body: "あいう"},
+		{ID: "101", Body: "あえお"},
+	})
+
+	// act
+	forgotten, err := handler.ForgetDeletedPosts(ctx, client, store)
+
+	// assert
+	if err != nil {
+		t.Fatalf("ForgetDeletedPosts() should not return error, but got: %v", err)
+	}
+	if want := []string{"102"}; !reflect.DeepEqual(want, forgotten) {
+		t.Fatalf("unexpected forgotten posts: want %v, but got %v", want, forgotten)
+	}
+	want := exportTransitions(t, buildProvenanceModel(t, []blog.Post{{ID: "103", Body: "あいう"}, {ID: "101", Body: "あえお"}}))
+	if got := exportTransitions(t, store); want != got {
+		t.Fatalf("unexpected model: want\n%s\nbut got\n%s", want, got)
+	}
+}
+
+func TestForgetDeletedPostsEvery_SkipsChecksWithinInterval(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	store := buildProvenanceModel(t, provenanceTestPosts)
+	checkedStore := persistence.NewMemoryStore()
+	newClient := func(posts ...blog.Post) blog.BlogClient {
+		return blog.NewRecordableBlogClientWithPosts(posts)
+	}
+
+	// act
+	first, err := handler.ForgetDeletedPostsEvery(ctx, newClient(provenanceTestPosts[0], provenanceTestPosts[2]), store, checkedStore, time.Hour)
+	if err != nil {
+		t.Fatalf("ForgetDeletedPostsEvery() should not return error, but got: %v", err)
+	}
+	// 103 is also deleted, but it is not checked within the interval
+	second, err := handler.ForgetDeletedPostsEvery(ctx, newClient(provenanceTestPosts[2]), store, checkedStore, time.Hour)
+	if err != nil {
+		t.Fatalf("ForgetDeletedPostsEvery() should not return error, but got: %v", err)
+	}
+
+	// assert
+	if want := []string{"102"}; !reflect.DeepEqual(want, first) {
+		t.Fatalf("unexpected forgotten posts: want %v, but got %v", want, first)
+	}
+	if second != nil {
+		t.Fatalf("posts should not be checked within the interval, but got: %v", second)
+	}
+}
+
+var provenanceTestPosts = []blog.Post{
+	{ID: "103", Body: "あいう"},
+	{ID: "102", Body: "あいえ"},
+	{ID: "101", Body: "あえお"},
+}
+
+func buildProvenanceModel(t *testing.T, posts []blog.Post) persistence.PersistentStore {
+	t.Helper()
+	store := persistence.NewMemoryStore()
+	client := blog.NewRecordableBlogClientWithPosts(posts)
+	if err := handler.BuildChain(context.Background(), client, &slowAnalyzer{}, store, handler.WithProvenance(true), handler.WithStateSize(1)); err != nil {
+		t.Fatalf("unexpected error while building chain: %v", err)
+	}
+	return store
+}
+
+// Exports transitions of the model without header lines, which include the build time.
+func exportTransitions(t *testing.T, store persistence.PersistentStore) string {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	if err := handler.ExportModel(context.Background(), store, buf); err != nil {
+		t.Fatalf("unexpected error while exporting model: %v", err)
+	}
+	lines := []string{}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/paralleltree/markov-bot-go/markov"
+	"github.com/paralleltree/markov-bot-go/persistence"
+)
+
+// Texts containing a keyword are found by generating repeatedly, so more attempts are allowed.
+const maxKeywordAttemptsCount = 10000
+
+// Sampler generates texts from a loaded model without posting them, for previewing the model.
+type Sampler struct {
+	model      *markov.FrozenChain
+	conf       *generatePostConf
+	vocabulary *vocabulary
+}
+
+// Loads the model and returns a sampler generating the number of texts specified by WithCandidatesCount on each call.
+func NewSampler(ctx context.Context, store persistence.PersistentStore, optFns ...func(*generatePostConf)) (*Sampler, error) {
+	chain, err := loadModel(ctx, store)
+	if err != nil {
+		return nil, fmt.Errorf("load model: %w", err)
+	}
+	model := chain.Freeze()
+	return &Sampler{
+		model:      model,
+		conf:       newGeneratePostConf(optFns...),
+		vocabulary: newVocabulary(model.Tokens()),
+	}, nil
+}
+
+// Generates texts containing keyword with their scores. An empty keyword matches any text.
+// Returns fewer texts than requested if they are not found within the limited number of attempts,
+// and no texts without attempts if keyword cannot be formed from tokens of the model.
+func (s *Sampler) Sample(keyword string) []Candidate {
+	attempts := maxAttemptsCount
+	if keyword != "" {
+		if !s.vocabulary.canForm(keyword) {
+			return []Candidate{}
+		}
+		attempts = maxKeywordAttemptsCount
+	}
+	return generateCandidates(s.model, s.conf, keyword, attempts)
+}
+
+// Tokens of a model, to tell whether a keyword can appear in generated texts.
+type vocabulary struct {
+	tokens map[string]bool
+	// tokens without their trailing whitespaces, which can end a keyword
+	trimmed map[string]bool
+}
+
+func newVocabulary(tokens []string) *vocabulary {
+	v := &vocabulary{
+		tokens:  make(map[string]bool, len(tokens)),
+		trimmed: make(map[string]bool, len(tokens)),
+	}
+	for _, token := range tokens {
+		v.tokens[token] = true
+		v.trimmed[strings.TrimRightFunc(token, unicode.IsSpace)] = true
+	}
+	return v
+}
+
+// Reports whether keyword can be split into consecutive tokens as containsKeyword matches it.
+// Spaces between tokens are allowed to be omitted from tokens, since models built before whitespaces were preserved
+// have tokens without them. A true result does not mean the tokens are generated consecutively.
+func (v *vocabulary) canForm(keyword string) bool {
+	reachable := make([]bool, len(keyword)+1)
+	reachable[0] = true
+	for i := 0; i < len(keyword); i++ {
+		if !reachable[i] {
+			continue
+		}
+		if keyword[i] == ' ' {
+			reachable[i+1] = true
+		}
+		for j := i + 1; j <= len(keyword); j++ {
+			if v.tokens[keyword[i:j]] || (j == len(keyword) && v.trimmed[keyword[i:j]]) {
+				reachable[j] = true
+			}
+		}
+	}
+	return reachable[len(keyword)]
+}
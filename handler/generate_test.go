@@ -0,0 +1,177 @@
+package handler_test
+
+import (
+	"context"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/paralleltree/markov-bot-go/blog"
+	"github.com/paralleltree/markov-bot-go/handler"
+	"github.com/paralleltree/markov-bot-go/markov"
+	"github.com/paralleltree/markov-bot-go/morpheme"
+	"github.com/paralleltree/markov-bot-go/persistence"
+)
+
+func TestSampler_Sample(t *testing.T) {
+	cases := []struct {
+		name          string
+		keyword       string
+		maxWordsCount int
+		check         func(c handler.Candidate) bool
+	}{
+		{
+			name:  "without restriction",
+			check: func(c handler.Candidate) bool { return c.Text != "" },
+		},
+		{
+			name:    "with keyword",
+			keyword: "か",
+			check:   func(c handler.Candidate) bool { return strings.Contains(c.Text, "か") },
+		},
+		{
+			name:          "with max words count",
+			maxWordsCount: 3,
+			check:         func(c handler.Candidate) bool { return c.WordsCount <= 3 },
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			ctx := context.Background()
+			fetchClient := blog.NewRecordableBlogClient([]string{"あい", "あいうえお", "いうえ", "うえおかき", "えお"})
+			store := persistence.NewMemoryStore()
+			if err := handler.BuildChain(ctx, fetchClient, morpheme.NewGraphemeAnalyzer(), store, handler.WithStateSize(1), handler.WithTokenUnit(markov.UnitCharacter)); err != nil {
+				t.Fatalf("BuildChain() should not return error, but got: %v", err)
+			}
+			count := 5
+			sampler, err := handler.NewSampler(ctx, store,
+				handler.WithCandidatesCount(count),
+				handler.WithMaxWordsCount(tt.maxWordsCount),
+				handler.WithRand(rand.New(rand.NewSource(1))))
+			if err != nil {
+				t.Fatalf("NewSampler() should not return error, but got: %v", err)
+			}
+
+			// act
+			got := sampler.Sample(tt.keyword)
+
+			// assert
+			if len(got) != count {
+				t.Fatalf("unexpected items count: want %d, but got %d", count, len(got))
+			}
+			for _, c := range got {
+				if !tt.check(c) {
+					t.Errorf("unexpected sample: %+v", c)
+				}
+			}
+		})
+	}
+}
+
+func TestSampler_Sample_WhenKeywordNotInModel_ReturnsNoText(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	fetchClient := blog.NewRecordableBlogClient([]string{"あいうえお"})
+	store := persistence.NewMemoryStore()
+	if err := handler.BuildChain(ctx, fetchClient, morpheme.NewGraphemeAnalyzer(), store, handler.WithTokenUnit(markov.UnitCharacter)); err != nil {
+		t.Fatalf("BuildChain() should not return error, but got: %v", err)
+	}
+	sampler, err := handler.NewSampler(ctx, store)
+	if err != nil {
+		t.Fatalf("NewSampler() should not return error, but got: %v", err)
+	}
+
+	// act
+	got := sampler.Sample("か")
+
+	// assert
+	if len(got) != 0 {
+		t.Fatalf("no text should be generated, but got: %+v", got)
+	}
+}
+
+// counts the random numbers drawn for generation
+type countingSource struct {
+	rand.Source
+	count int
+}
+
+func (s *countingSource) Int63() int64 {
+	s.count++
+	return s.Source.Int63()
+}
+
+func TestSampler_Sample_WhenKeywordNotInVocabulary_ReturnsWithoutGenerating(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	fetchClient := blog.NewRecordableBlogClient([]string{"a cat sleeps", "a dog runs"})
+	store := persistence.NewMemoryStore()
+	if err := handler.BuildChain(ctx, fetchClient, morpheme.NewEnglishAnalyzer(), store, handler.WithStateSize(1)); err != nil {
+		t.Fatalf("BuildChain() should not return error, but got: %v", err)
+	}
+	source := &countingSource{Source: rand.NewSource(1)}
+	sampler, err := handler.NewSampler(ctx, store, handler.WithRand(rand.New(source)))
+	if err != nil {
+		t.Fatalf("NewSampler() should not return error, but got: %v", err)
+	}
+
+	// act
+	got := sampler.Sample("bird")
+
+	// assert
+	if len(got) != 0 {
+		t.Fatalf("no text should be generated, but got: %+v", got)
+	}
+	if source.count != 0 {
+		t.Errorf("no text should be generated for a keyword not in the vocabulary, but drew %d random numbers", source.count)
+	}
+}
+
+func TestSampler_Sample_WithKeyword_MatchesWholeTokens(t *testing.T) {
+	cases := []struct {
+		name    string
+		keyword string
+		want    []string
+	}{
+		{name: "word", keyword: "cat", want: []string{"a cat sleeps"}},
+		{name: "consecutive words", keyword: "a cat", want: []string{"a cat sleeps"}},
+		{name: "part of a word", keyword: "categ", want: []string{}},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			ctx := context.Background()
+			fetchClient := blog.NewRecordableBlogClient([]string{"a category grows", "a cat sleeps"})
+			store := persistence.NewMemoryStore()
+			if err := handler.BuildChain(ctx, fetchClient, morpheme.NewEnglishAnalyzer(), store, handler.WithStateSize(2)); err != nil {
+				t.Fatalf("BuildChain() should not return error, but got: %v", err)
+			}
+			sampler, err := handler.NewSampler(ctx, store,
+				handler.WithCandidatesCount(3),
+				handler.WithRand(rand.New(rand.NewSource(1))))
+			if err != nil {
+				t.Fatalf("NewSampler() should not return error, but got: %v", err)
+			}
+
+			// act
+			got := sampler.Sample(tt.keyword)
+
+			// assert
+			if len(tt.want) == 0 && len(got) != 0 {
+				t.Fatalf("no text should be generated, but got: %+v", got)
+			}
+			for _, c := range got {
+				if !slices.Contains(tt.want, c.Text) {
+					t.Errorf("unexpected sample: want one of %q, but got %q", tt.want, c.Text)
+				}
+			}
+			if len(tt.want) != 0 && len(got) == 0 {
+				t.Fatalf("texts containing %q should be generated", tt.keyword)
+			}
+		})
+	}
+}
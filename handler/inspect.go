@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/paralleltree/markov-bot-go/markov"
+	"github.com/paralleltree/markov-bot-go/persistence"
+)
+
+// ModelReport describes a saved model.
+type ModelReport struct {
+	ModifiedAt time.Time `json:"modified_at"`
+	// Nil if the model was built without metadata.
+	Metadata *markov.Metadata `json:"metadata,omitempty"`
+	markov.Stats
+}
+
+// Loads the model and returns its statistics with the topN most frequent tokens.
+func InspectModel(ctx context.Context, store persistence.PersistentStore, topN int) (*ModelReport, error) {
+	modTime, ok, err := store.ModTime(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get modtime: %w", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("model does not exist")
+	}
+
+	chain, err := loadModel(ctx, store)
+	if err != nil {
+		return nil, fmt.Errorf("load model: %w", err)
+	}
+
+	return &ModelReport{
+		ModifiedAt: modTime,
+		Metadata:   chain.Metadata,
+		Stats:      chain.Stats(topN),
+	}, nil
+}
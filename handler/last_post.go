@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/paralleltree/markov-bot-go/persistence"
+)
+
+// Reports whether a post recorded by RecordPost was made within the interval before now,
+// so that retried or overlapping runs do not post twice.
+func PostedWithin(ctx context.Context, store persistence.PersistentStore, interval time.Duration) (bool, error) {
+	return recordedWithin(ctx, store, interval, "last post")
+}
+
+// Records the time of the post to be checked by PostedWithin.
+func RecordPost(ctx context.Context, store persistence.PersistentStore, postedAt time.Time) error {
+	return recordTime(ctx, store, postedAt, "last post")
+}
+
+// Reports whether the time saved in the store by recordTime is within the interval before now.
+func recordedWithin(ctx context.Context, store persistence.PersistentStore, interval time.Duration, name string) (bool, error) {
+	_, ok, err := store.ModTime(ctx)
+	if err != nil {
+		return false, fmt.Errorf("get modtime: %w", err)
+	}
+	if !ok {
+		return false, nil
+	}
+
+	data, err := store.Load(ctx)
+	if err != nil {
+		return false, fmt.Errorf("load %s: %w", name, err)
+	}
+	recordedAt, err := time.Parse(time.RFC3339Nano, string(data))
+	if err != nil {
+		return false, fmt.Errorf("parse %s time: %w", name, err)
+	}
+	return time.Since(recordedAt) < interval, nil
+}
+
+func recordTime(ctx context.Context, store persistence.PersistentStore, t time.Time, name string) error {
+	if err := store.Save(ctx, []byte(t.Format(time.RFC3339Nano))); err != nil {
+		return fmt.Errorf("save %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package handler_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/paralleltree/markov-bot-go/handler"
+	"github.com/paralleltree/markov-bot-go/persistence"
+)
+
+func TestPostedWithin(t *testing.T) {
+	cases := []struct {
+		name     string
+		postedAt time.Time
+		want     bool
+	}{
+		{name: "not posted"},
+		{name: "posted recently", postedAt: time.Now().Add(-time.Minute), want: true},
+		{name: "posted long ago", postedAt: time.Now().Add(-time.Hour)},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			ctx := context.Background()
+			store := persistence.NewMemoryStore()
+			if !tt.postedAt.IsZero() {
+				if err := handler.RecordPost(ctx, store, tt.postedAt); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			// act
+			got, err := handler.PostedWithin(ctx, store, 10*time.Minute)
+
+			// assert
+			if err != nil {
+				t.Fatalf("PostedWithin() should not return error, but got: %v", err)
+			}
+			if tt.want != got {
+				t.Fatalf("want %v, but got %v", tt.want, got)
+			}
+		})
+	}
+}
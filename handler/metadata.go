@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/paralleltree/markov-bot-go/markov"
+	"github.com/paralleltree/markov-bot-go/persistence"
+)
+
+// Returns the time the saved model was built, and false if the model does not exist.
+// The build time recorded in the model is preferred over the modification time of the store,
+// which can be reset by copying or restoring the model.
+// Falls back to the modification time for models built without metadata.
+func ModelBuiltAt(ctx context.Context, store persistence.PersistentStore) (time.Time, bool, error) {
+	return modelBuiltAt(ctx, store, func() (*markov.Metadata, error) {
+		r, err := persistence.OpenStream(ctx, store)
+		if err != nil {
+			return nil, fmt.Errorf("load model: %w", err)
+		}
+		defer r.Close()
+		return markov.ReadMetadata(r)
+	})
+}
+
+func modelBuiltAt(ctx context.Context, store persistence.PersistentStore, loadMetadata func() (*markov.Metadata, error)) (time.Time, bool, error) {
+	modTime, ok, err := store.ModTime(ctx)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("get modtime: %w", err)
+	}
+	if !ok {
+		return time.Time{}, false, nil
+	}
+
+	metadata, err := loadMetadata()
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("load metadata: %w", err)
+	}
+	if metadata == nil || metadata.BuiltAt.IsZero() {
+		return modTime, true, nil
+	}
+	return metadata.BuiltAt, true, nil
+}
+
+// Describes a saved model by its metadata, to be recorded for each version in versioned stores.
+// Models without metadata are described as nil.
+func DescribeModel(r io.Reader) (json.RawMessage, error) {
+	metadata, err := markov.ReadMetadata(r)
+	if err != nil {
+		return nil, fmt.Errorf("load metadata: %w", err)
+	}
+	if metadata == nil {
+		return nil, nil
+	}
+	return json.Marshal(metadata)
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/paralleltree/markov-bot-go/blog"
+	"github.com/paralleltree/markov-bot-go/lib"
+	"github.com/paralleltree/markov-bot-go/morpheme"
+)
+
+type fetchedStatus struct {
+	index  int
+	status blog.Post
+}
+
+type analyzedStatus struct {
+	index     int
+	status    blog.Post
+	sentences [][]string
+}
+
+// Fetches up to count statuses in a goroutine and analyzes them with a pool of workers.
+// The iterator is built by newIterator with the context of the pipeline,
+// so that fetching is canceled as soon as the pipeline stops.
+// Results are passed to consume in order of fetching regardless of the number of workers,
+// so that the consumer always observes the same sequence.
+// The analyzer must be safe for concurrent use if concurrency is greater than 1.
+func analyzeStatuses(
+	ctx context.Context,
+	newIterator func(ctx context.Context) lib.IteratorFunc[blog.Post],
+	analyzer morpheme.MorphemeAnalyzer,
+	count int,
+	concurrency int,
+	consume func(status blog.Post, sentences [][]string),
+) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	pipelineCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	statuses := make(chan fetchedStatus, concurrency)
+	results := make(chan analyzedStatus, concurrency)
+	// buffered so that failing goroutines never block
+	errs := make(chan error, concurrency+1)
+
+	iterator := newIterator(pipelineCtx)
+	go func() {
+		defer close(statuses)
+		for i := 0; i < count; i++ {
+			status, hasNext, err := iterator()
+			if err != nil {
+				errs <- fmt.Errorf("fetch statuses: %w", err)
+				return
+			}
+			if !hasNext {
+				return
+			}
+			select {
+			case statuses <- fetchedStatus{index: i, status: status}:
+			case <-pipelineCtx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for s := range statuses {
+				sentences, err := analyzer.Analyze(s.status.Body)
+				if err != nil {
+					errs <- fmt.Errorf("analyze text: %w", err)
+					return
+				}
+				select {
+				case results <- analyzedStatus{index: s.index, status: s.status, sentences: sentences}:
+				case <-pipelineCtx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// reorder results arriving out of order
+	pending := map[int]analyzedStatus{}
+	next := 0
+	for {
+		select {
+		case err := <-errs:
+			return err
+
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case r, ok := <-results:
+			if !ok {
+				// an error may be sent just before the results are closed
+				select {
+				case err := <-errs:
+					return err
+				default:
+				}
+				return ctx.Err()
+			}
+			pending[r.index] = r
+			for {
+				r, ok := pending[next]
+				if !ok {
+					break
+				}
+				consume(r.status, r.sentences)
+				delete(pending, next)
+				next++
+			}
+		}
+	}
+}
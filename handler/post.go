@@ -3,8 +3,10 @@ package handler
 import (
 	"context"
 	"fmt"
-	"regexp"
+	"io"
+	"math/rand"
 	"strings"
+	"unicode"
 
 	"github.com/paralleltree/markov-bot-go/blog"
 	"github.com/paralleltree/markov-bot-go/markov"
@@ -17,6 +19,15 @@ var ErrGenerationFailed = fmt.Errorf("failed to generate a post")
 
 type generatePostConf struct {
 	minWordsCount int
+	maxWordsCount int
+	backoff       markov.BackoffOptions
+	sampling      markov.SamplingOptions
+	rand          *rand.Rand
+
+	candidatesCount  int
+	scoring          ScoringOptions
+	candidatesWriter io.Writer
+	cache            *ModelCache
 }
 
 func WithMinWordsCount(minWordsCount int) func(c *generatePostConf) {
@@ -25,62 +36,165 @@ func WithMinWordsCount(minWordsCount int) func(c *generatePostConf) {
 	}
 }
 
-func GenerateAndPost(ctx context.Context, client blog.BlogClient, store persistence.PersistentStore, optFns ...func(*generatePostConf)) error {
+// Discards generated texts with more words than maxWordsCount. Zero means no limit.
+func WithMaxWordsCount(maxWordsCount int) func(c *generatePostConf) {
+	return func(c *generatePostConf) {
+		c.maxWordsCount = maxWordsCount
+	}
+}
+
+// Sets thresholds to back off to shorter contexts. This takes effect on variable-order models only.
+func WithBackoff(opts markov.BackoffOptions) func(c *generatePostConf) {
+	return func(c *generatePostConf) {
+		c.backoff = opts
+	}
+}
+
+func WithSampling(opts markov.SamplingOptions) func(c *generatePostConf) {
+	return func(c *generatePostConf) {
+		c.sampling = opts
+	}
+}
+
+// Sets the random source used for generation. If nil, the global source of math/rand is used.
+// With a seeded source, a given model always generates the same post.
+func WithRand(r *rand.Rand) func(c *generatePostConf) {
+	return func(c *generatePostConf) {
+		c.rand = r
+	}
+}
+
+// Generates the number of candidates and posts the one with the highest score.
+func WithCandidatesCount(count int) func(c *generatePostConf) {
+	return func(c *generatePostConf) {
+		if count > 0 {
+			c.candidatesCount = count
+		}
+	}
+}
+
+func WithScoring(opts ScoringOptions) func(c *generatePostConf) {
+	return func(c *generatePostConf) {
+		c.scoring = opts
+	}
+}
+
+// Writes candidates and their scores to w, for tuning scoring options.
+func WithCandidatesWriter(w io.Writer) func(c *generatePostConf) {
+	return func(c *generatePostConf) {
+		c.candidatesWriter = w
+	}
+}
+
+// Loads the model through the cache, to skip loading it again while it is not modified.
+func WithModelCache(cache *ModelCache) func(c *generatePostConf) {
+	return func(c *generatePostConf) {
+		c.cache = cache
+	}
+}
+
+func newGeneratePostConf(optFns ...func(*generatePostConf)) *generatePostConf {
 	conf := &generatePostConf{
 		minWordsCount: 1,
+		backoff:       markov.DefaultBackoffOptions(),
+		sampling:      markov.DefaultSamplingOptions(),
+
+		candidatesCount: 1,
 	}
 	for _, f := range optFns {
 		f(conf)
 	}
+	return conf
+}
+
+func GenerateAndPost(ctx context.Context, client blog.BlogClient, store persistence.PersistentStore, optFns ...func(*generatePostConf)) error {
+	conf := newGeneratePostConf(optFns...)
 
-	model, err := loadModel(ctx, store)
+	cache := conf.cache
+	if cache == nil {
+		cache = NewModelCache()
+	}
+	model, _, err := cache.load(ctx, store)
 	if err != nil {
 		return fmt.Errorf("load model: %w", err)
 	}
 
-	for i := 0; i < maxAttemptsCount; i++ {
-		generated := model.Generate()
+	candidates := generateCandidates(model, conf, "", maxAttemptsCount)
+	if len(candidates) == 0 {
+		return ErrGenerationFailed
+	}
+
+	chosen := bestCandidate(candidates)
+	if conf.candidatesWriter != nil {
+		if err := writeCandidates(conf.candidatesWriter, candidates, chosen); err != nil {
+			return fmt.Errorf("write candidates: %w", err)
+		}
+	}
+
+	if err := client.CreatePost(ctx, candidates[chosen].Text); err != nil {
+		return fmt.Errorf("create status: %w", err)
+	}
+	return nil
+}
+
+// Generates up to conf.candidatesCount candidates containing keyword as whole tokens within the number of attempts.
+// An empty keyword matches any text.
+func generateCandidates(model *markov.FrozenChain, conf *generatePostConf, keyword string, attempts int) []Candidate {
+	candidates := make([]Candidate, 0, conf.candidatesCount)
+	for i := 0; i < attempts && len(candidates) < conf.candidatesCount; i++ {
+		generated := model.GenerateWithOptions(markov.WithBackoff(conf.backoff), markov.WithSampling(conf.sampling), markov.WithRand(conf.rand))
 		if len(generated) < conf.minWordsCount {
 			continue
 		}
-		text := strings.Join(postprocessSentence(generated), "")
+		if 0 < conf.maxWordsCount && conf.maxWordsCount < len(generated) {
+			continue
+		}
+		if !containsKeyword(model, generated, keyword) {
+			continue
+		}
+		candidates = append(candidates, newCandidate(model, generated, conf.scoring))
+	}
+	return candidates
+}
 
-		if err := client.CreatePost(ctx, text); err != nil {
-			return fmt.Errorf("create status: %w", err)
+// Reports whether consecutive whole tokens form keyword, ignoring their trailing whitespaces,
+// so that a keyword does not match a part of a word, e.g. "cat" in "category".
+// An empty keyword matches any tokens.
+func containsKeyword(model *markov.FrozenChain, tokens []string, keyword string) bool {
+	if keyword == "" {
+		return true
+	}
+	for i := range tokens {
+		for j := i + 1; j <= len(tokens); j++ {
+			joined := strings.TrimRightFunc(model.Join(tokens[i:j]), unicode.IsSpace)
+			if joined == keyword {
+				return true
+			}
+			if !strings.HasPrefix(keyword, joined) {
+				break
+			}
 		}
-		return nil
 	}
-	return ErrGenerationFailed
+	return false
 }
 
 func loadModel(ctx context.Context, store persistence.PersistentStore) (*markov.Chain, error) {
-	data, err := store.Load(ctx)
+	r, err := persistence.OpenStream(ctx, store)
 	if err != nil {
 		return nil, fmt.Errorf("load chain data: %w", err)
 	}
-	chain, err := markov.LoadChain(data)
+	defer r.Close()
+
+	chain, err := markov.ReadChain(r)
 	if err != nil {
 		return nil, fmt.Errorf("reconstruct chain: %w", err)
 	}
 	return chain, nil
 }
 
-func postprocessSentence(input []string) []string {
-	if len(input) < 1 {
-		return input
-	}
-
-	result := make([]string, 0, len(input))
-	result = append(result, input[0])
-	wordPattern := regexp.MustCompile(`^[A-Za-z]+$`)
-
-	for i, word := range input[1:] {
-		prev := input[i]
-		if wordPattern.MatchString(prev) && wordPattern.MatchString(word) {
-			result = append(result, " ", word)
-			continue
-		}
-		result = append(result, word)
+func saveModel(ctx context.Context, chain *markov.Chain, store persistence.PersistentStore) error {
+	if err := persistence.SaveStream(ctx, store, chain.DumpTo); err != nil {
+		return fmt.Errorf("save chain: %w", err)
 	}
-	return result
+	return nil
 }
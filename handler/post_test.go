@@ -1,12 +1,16 @@
 package handler_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"math/rand"
+	"strings"
 	"testing"
 
 	"github.com/paralleltree/markov-bot-go/blog"
 	"github.com/paralleltree/markov-bot-go/handler"
+	"github.com/paralleltree/markov-bot-go/markov"
 	"github.com/paralleltree/markov-bot-go/morpheme"
 	"github.com/paralleltree/markov-bot-go/persistence"
 )
@@ -56,6 +60,10 @@ func TestGenerateAndPost_ReturnsTextFromModel(t *testing.T) {
 		{
 			inputText: "EZ DO DANCE",
 		},
+		{
+			// spaces around symbols and contractions
+			inputText: "I don't know e.g. 12 : 00",
+		},
 	}
 
 	for _, tt := range cases {
@@ -86,3 +94,104 @@ func TestGenerateAndPost_ReturnsTextFromModel(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateAndPost_WithCharacterChain_ReturnsTextFromModel(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	inputText := "👨‍👩‍👧家族でお出かけ"
+	postClient := blog.NewRecordableBlogClient(nil)
+	fetchClient := blog.NewRecordableBlogClient([]string{inputText})
+	store := persistence.NewMemoryStore()
+
+	if err := handler.BuildChain(ctx, fetchClient, morpheme.NewGraphemeAnalyzer(), store, handler.WithTokenUnit(markov.UnitCharacter)); err != nil {
+		t.Fatalf("BuildChain() should not return error, but got: %v", err)
+	}
+
+	// act
+	err := handler.GenerateAndPost(ctx, postClient, store)
+
+	// assert
+	if err != nil {
+		t.Fatalf("GenerateAndPost() should not return error, but got: %v", err)
+	}
+	if len(postClient.PostedContents) != 1 {
+		t.Fatalf("unexpected items count: want %d, but got %d", 1, len(postClient.PostedContents))
+	}
+	if inputText != postClient.PostedContents[0] {
+		t.Errorf("unexpected output: want %s, but got %s", inputText, postClient.PostedContents[0])
+	}
+}
+
+func TestGenerateAndPost_WithSameSeed_PostsSameText(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	postClient := blog.NewRecordableBlogClient(nil)
+	fetchClient := blog.NewRecordableBlogClient([]string{"あいうえお", "いえあおう", "おういあえ", "えおあいう"})
+	store := persistence.NewMemoryStore()
+
+	if err := handler.BuildChain(ctx, fetchClient, morpheme.NewGraphemeAnalyzer(), store, handler.WithStateSize(1), handler.WithTokenUnit(markov.UnitCharacter)); err != nil {
+		t.Fatalf("BuildChain() should not return error, but got: %v", err)
+	}
+
+	// act
+	for i := 0; i < 5; i++ {
+		if err := handler.GenerateAndPost(ctx, postClient, store, handler.WithRand(rand.New(rand.NewSource(42)))); err != nil {
+			t.Fatalf("GenerateAndPost() should not return error, but got: %v", err)
+		}
+	}
+
+	// assert
+	for _, posted := range postClient.PostedContents[1:] {
+		if postClient.PostedContents[0] != posted {
+			t.Fatalf("posts should be same with the same seed: %v", postClient.PostedContents)
+		}
+	}
+}
+
+func TestGenerateAndPost_WithCandidates_PostsCandidateWithHighestScore(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	postClient := blog.NewRecordableBlogClient(nil)
+	fetchClient := blog.NewRecordableBlogClient([]string{"あい", "あいうえお", "いうえ", "うえおかき", "えお"})
+	store := persistence.NewMemoryStore()
+	candidatesCount := 10
+
+	if err := handler.BuildChain(ctx, fetchClient, morpheme.NewGraphemeAnalyzer(), store, handler.WithStateSize(1), handler.WithTokenUnit(markov.UnitCharacter)); err != nil {
+		t.Fatalf("BuildChain() should not return error, but got: %v", err)
+	}
+
+	// act
+	buf := new(bytes.Buffer)
+	err := handler.GenerateAndPost(
+		ctx,
+		postClient,
+		store,
+		handler.WithCandidatesCount(candidatesCount),
+		handler.WithScoring(handler.ScoringOptions{LengthWeight: 1}),
+		handler.WithCandidatesWriter(buf),
+		handler.WithRand(rand.New(rand.NewSource(1))),
+	)
+
+	// assert
+	if err != nil {
+		t.Fatalf("GenerateAndPost() should not return error, but got: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != candidatesCount {
+		t.Fatalf("unexpected candidates count: want %d, but got %d", candidatesCount, len(lines))
+	}
+	longest := ""
+	chosen := ""
+	for _, line := range lines {
+		text := line[strings.LastIndex(line, "\t")+1:]
+		if len([]rune(longest)) < len([]rune(text)) {
+			longest = text
+		}
+		if strings.HasPrefix(line, "*") {
+			chosen = text
+		}
+	}
+	if longest != postClient.PostedContents[0] || chosen != postClient.PostedContents[0] {
+		t.Fatalf("the longest candidate %q should be posted, but got %q", longest, postClient.PostedContents[0])
+	}
+}
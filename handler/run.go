@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/paralleltree/markov-bot-go/blog"
+	"github.com/paralleltree/markov-bot-go/morpheme"
+	"github.com/paralleltree/markov-bot-go/persistence"
+)
+
+// BuildChainOption configures BuildChain.
+type BuildChainOption = func(*buildChainConf)
+
+// GeneratePostOption configures GenerateAndPost and NewSampler.
+type GeneratePostOption = func(*generatePostConf)
+
+// RunOption configures Run.
+type RunOption = func(*runConf)
+
+type runConf struct {
+	expiresIn             time.Duration
+	lastPostStore         persistence.PersistentStore
+	minPostInterval       time.Duration
+	recordPost            bool
+	forgetCheckedStore    persistence.PersistentStore
+	forgetDeletedInterval time.Duration
+	buildOpts             []BuildChainOption
+	generateOpts          []GeneratePostOption
+	logWriter             io.Writer
+}
+
+// Rebuilds the model if it was built before this duration.
+func WithExpiresIn(expiresIn time.Duration) func(c *runConf) {
+	return func(c *runConf) {
+		c.expiresIn = expiresIn
+	}
+}
+
+// Skips posting if a post was recorded in the store within the interval, and records posts in it.
+// Zero or less disables the check.
+func WithMinPostInterval(store persistence.PersistentStore, interval time.Duration) func(c *runConf) {
+	return func(c *runConf) {
+		c.lastPostStore = store
+		c.minPostInterval = interval
+	}
+}
+
+// Checks the interval given by WithMinPostInterval without recording the post, e.g. on dry runs.
+func WithoutRecordingPost() func(c *runConf) {
+	return func(c *runConf) {
+		c.recordPost = false
+	}
+}
+
+// Forgets deleted posts once in the interval before posting from a model which is not rebuilt.
+// The time of the last check is recorded in checkedStore. See ForgetDeletedPostsEvery for details.
+func WithForgetDeleted(checkedStore persistence.PersistentStore, interval time.Duration) func(c *runConf) {
+	return func(c *runConf) {
+		c.forgetCheckedStore = checkedStore
+		c.forgetDeletedInterval = interval
+	}
+}
+
+func WithBuildOptions(opts ...BuildChainOption) func(c *runConf) {
+	return func(c *runConf) {
+		c.buildOpts = append(c.buildOpts, opts...)
+	}
+}
+
+// Sets options to generate the post. If WithModelCache is included, the model is checked for expiry through the cache.
+func WithGenerateOptions(opts ...GeneratePostOption) func(c *runConf) {
+	return func(c *runConf) {
+		c.generateOpts = append(c.generateOpts, opts...)
+	}
+}
+
+// Writes messages about skipped steps and failures which do not stop the run. Defaults to stderr.
+func WithLogWriter(w io.Writer) func(c *runConf) {
+	return func(c *runConf) {
+		c.logWriter = w
+	}
+}
+
+// Posts a text generated from the model, building the model first if it does not exist or is expired.
+// The run holds the lock acquired by lock, and is skipped without error if lock returns persistence.ErrLocked.
+// A model which exists is used even if rebuilding it fails.
+func Run(ctx context.Context, lock func(context.Context) (func() error, error), fetchClient blog.BlogClient, analyzer morpheme.MorphemeAnalyzer, postClient blog.BlogClient, store persistence.PersistentStore, optFns ...RunOption) error {
+	conf := &runConf{
+		expiresIn:  24 * time.Hour,
+		recordPost: true,
+		logWriter:  os.Stderr,
+	}
+	for _, f := range optFns {
+		f(conf)
+	}
+
+	// overlapping runs, e.g. retried ones, are skipped instead of building and posting twice
+	unlock, err := lock(ctx)
+	if errors.Is(err, persistence.ErrLocked) {
+		fmt.Fprintln(conf.logWriter, "another run is holding the lock; skipped")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("lock: %w", err)
+	}
+	defer func() {
+		if err := unlock(); err != nil {
+			fmt.Fprintf(conf.logWriter, "unlock: %v\n", err)
+		}
+	}()
+
+	if conf.lastPostStore != nil && conf.minPostInterval > 0 {
+		posted, err := PostedWithin(ctx, conf.lastPostStore, conf.minPostInterval)
+		if err != nil {
+			return fmt.Errorf("check last post: %w", err)
+		}
+		if posted {
+			fmt.Fprintln(conf.logWriter, "posted within the interval; skipped")
+			return nil
+		}
+	}
+
+	// the model checked for expiry is reused for generation unless it is modified
+	generateOpts := conf.generateOpts
+	cache := newGeneratePostConf(generateOpts...).cache
+	if cache == nil {
+		cache = NewModelCache()
+		generateOpts = append(generateOpts, WithModelCache(cache))
+	}
+	builtAt, ok, err := cache.ModelBuiltAt(ctx, store)
+	if err != nil {
+		return fmt.Errorf("get build time: %w", err)
+	}
+
+	// a rolled back model is kept as it is, so it is neither rebuilt nor changed by forgetting posts
+	pinned, err := persistence.IsPinned(ctx, store)
+	if err != nil {
+		return fmt.Errorf("check pinned: %w", err)
+	}
+
+	rebuilt := false
+	if !ok {
+		// return an error if initial build fails
+		if err := BuildChain(ctx, fetchClient, analyzer, store, conf.buildOpts...); err != nil {
+			return fmt.Errorf("build chain: %w", err)
+		}
+		rebuilt = true
+	} else if conf.expiresIn < time.Since(builtAt) {
+		// attempt to build chain if expired
+		// when building chain fails, it will use the existing chain
+		if pinned {
+			fmt.Fprintln(conf.logWriter, "model is pinned; skipped building")
+		} else if err := BuildChain(ctx, fetchClient, analyzer, store, conf.buildOpts...); err != nil {
+			fmt.Fprintf(conf.logWriter, "build chain: %v\n", err)
+		} else {
+			rebuilt = true
+		}
+	}
+
+	// a rebuilt chain does not contain deleted statuses
+	if conf.forgetCheckedStore != nil && !rebuilt && !pinned {
+		if _, err := ForgetDeletedPostsEvery(ctx, fetchClient, store, conf.forgetCheckedStore, conf.forgetDeletedInterval); err != nil {
+			fmt.Fprintf(conf.logWriter, "forget deleted posts: %v\n", err)
+		}
+	}
+
+	if err := GenerateAndPost(ctx, postClient, store, generateOpts...); err != nil {
+		return fmt.Errorf("generate and post: %w", err)
+	}
+	if conf.lastPostStore != nil && conf.minPostInterval > 0 && conf.recordPost {
+		if err := RecordPost(ctx, conf.lastPostStore, time.Now()); err != nil {
+			return fmt.Errorf("record post: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package handler_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/paralleltree/markov-bot-go/blog"
+	"github.com/paralleltree/markov-bot-go/handler"
+	"github.com/paralleltree/markov-bot-go/lib"
+	"github.com/paralleltree/markov-bot-go/markov"
+	"github.com/paralleltree/markov-bot-go/morpheme"
+	"github.com/paralleltree/markov-bot-go/persistence"
+)
+
+func runWithCharacterChain(ctx context.Context, locker persistence.Locker, fetchClient, postClient blog.BlogClient, store persistence.PersistentStore, optFns ...handler.RunOption) error {
+	opts := append([]handler.RunOption{
+		handler.WithBuildOptions(handler.WithTokenUnit(markov.UnitCharacter)),
+		handler.WithLogWriter(io.Discard),
+	}, optFns...)
+	return handler.Run(ctx, locker.TryLock, fetchClient, morpheme.NewGraphemeAnalyzer(), postClient, store, opts...)
+}
+
+func TestRun_WhenModelNotExists_BuildsModelAndPosts(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	inputText := "アルミ缶の上にあるミカン"
+	postClient := blog.NewRecordableBlogClient(nil)
+	store := persistence.NewMemoryStore()
+
+	// act
+	err := runWithCharacterChain(ctx, persistence.NewMemoryLocker(), blog.NewRecordableBlogClient([]string{inputText}), postClient, store)
+
+	// assert
+	if err != nil {
+		t.Fatalf("Run() should not return error, but got: %v", err)
+	}
+	if want := []string{inputText}; !reflect.DeepEqual(want, postClient.PostedContents) {
+		t.Errorf("unexpected output: want %v, but got %v", want, postClient.PostedContents)
+	}
+	if _, ok, _ := store.ModTime(ctx); !ok {
+		t.Errorf("Run() should save the built model")
+	}
+}
+
+func TestRun_WhenLocked_SkipsWithoutError(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	postClient := blog.NewRecordableBlogClient(nil)
+	locker := persistence.NewMemoryLocker()
+	if _, err := locker.TryLock(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// act
+	err := runWithCharacterChain(ctx, locker, blog.NewRecordableBlogClient([]string{"ミカン"}), postClient, persistence.NewMemoryStore())
+
+	// assert
+	if err != nil {
+		t.Fatalf("Run() should not return error, but got: %v", err)
+	}
+	if len(postClient.PostedContents) != 0 {
+		t.Errorf("Run() should not post, but got: %v", postClient.PostedContents)
+	}
+}
+
+func TestRun_WithMinPostInterval(t *testing.T) {
+	cases := []struct {
+		name        string
+		optFns      []handler.RunOption
+		wantPosted  int
+		wantTimeSet bool
+	}{
+		{name: "records posts", wantPosted: 1, wantTimeSet: true},
+		{name: "without recording posts", optFns: []handler.RunOption{handler.WithoutRecordingPost()}, wantPosted: 2},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			ctx := context.Background()
+			postClient := blog.NewRecordableBlogClient(nil)
+			fetchClient := blog.NewRecordableBlogClient([]string{"ミカン"})
+			store := persistence.NewMemoryStore()
+			lastPostStore := persistence.NewMemoryStore()
+			opts := append([]handler.RunOption{handler.WithMinPostInterval(lastPostStore, time.Minute)}, tt.optFns...)
+
+			// act
+			for i := 0; i < 2; i++ {
+				if err := runWithCharacterChain(ctx, persistence.NewMemoryLocker(), fetchClient, postClient, store, opts...); err != nil {
+					t.Fatalf("Run() should not return error, but got: %v", err)
+				}
+			}
+
+			// assert
+			if tt.wantPosted != len(postClient.PostedContents) {
+				t.Errorf("unexpected posts count: want %d, but got %d", tt.wantPosted, len(postClient.PostedContents))
+			}
+			if _, ok, _ := lastPostStore.ModTime(ctx); tt.wantTimeSet != ok {
+				t.Errorf("unexpected recording of the post: want %v, but got %v", tt.wantTimeSet, ok)
+			}
+		})
+	}
+}
+
+func TestRun_WhenModelIsExpiredAndBuildingFails_PostsWithExistingModel(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	inputText := "アルミ缶の上にあるミカン"
+	store := persistence.NewMemoryStore()
+	if err := runWithCharacterChain(ctx, persistence.NewMemoryLocker(), blog.NewRecordableBlogClient([]string{inputText}), blog.NewRecordableBlogClient(nil), store); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	postClient := blog.NewRecordableBlogClient(nil)
+
+	// act
+	err := runWithCharacterChain(ctx, persistence.NewMemoryLocker(), &failingBlogClient{}, postClient, store, handler.WithExpiresIn(0))
+
+	// assert
+	if err != nil {
+		t.Fatalf("Run() should not return error, but got: %v", err)
+	}
+	if want := []string{inputText}; !reflect.DeepEqual(want, postClient.PostedContents) {
+		t.Errorf("unexpected output: want %v, but got %v", want, postClient.PostedContents)
+	}
+}
+
+// fails to fetch posts
+type failingBlogClient struct {
+	blog.BlogClient
+}
+
+func (c *failingBlogClient) GetPostsFetcher(ctx context.Context) lib.ChunkIteratorFunc[blog.Post] {
+	return func() ([]blog.Post, bool, error) {
+		return nil, false, fmt.Errorf("failed to fetch posts")
+	}
+}
@@ -40,3 +40,18 @@ func BuildIterator[T any](chunkIterator ChunkIteratorFunc[T]) IteratorFunc[T] {
 		return buffer[current-1], current-1 < len(buffer), nil
 	}
 }
+
+// Returns an iterator which skips items not satisfying the predicate.
+func FilterIterator[T any](iterator IteratorFunc[T], predicate func(T) bool) IteratorFunc[T] {
+	return func() (T, bool, error) {
+		for {
+			item, ok, err := iterator()
+			if err != nil || !ok {
+				return item, ok, err
+			}
+			if predicate(item) {
+				return item, true, nil
+			}
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package lib_test
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/paralleltree/markov-bot-go/lib"
@@ -110,3 +111,31 @@ func TestBuildIterator_IteratesOverMultipleChunks(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterIterator_SkipsItemsNotSatisfyingPredicate(t *testing.T) {
+	// arrange
+	items := []int{1, 2, 3, 4, 5}
+	iterator := lib.BuildIterator(func() ([]int, bool, error) {
+		return items, false, nil
+	})
+	want := []int{2, 4}
+
+	// act
+	filtered := lib.FilterIterator(iterator, func(v int) bool { return v%2 == 0 })
+	got := []int{}
+	for {
+		v, ok, err := filtered()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !ok {
+			break
+		}
+		got = append(got, v)
+	}
+
+	// assert
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("unexpected result: want %v, but got %v", want, got)
+	}
+}
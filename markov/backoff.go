@@ -0,0 +1,34 @@
+package markov
+
+// Thresholds to decide whether a context is reliable enough to predict the next token.
+// A context not satisfying them is shortened by dropping its oldest token.
+type BackoffOptions struct {
+	// The minimum number of distinct tokens following the context.
+	// Contexts followed by a single token tend to copy the source verbatim.
+	MinContinuations int
+	// The minimum number of times the context was followed by any token.
+	MinOccurrences int
+}
+
+func DefaultBackoffOptions() BackoffOptions {
+	return BackoffOptions{
+		MinContinuations: 2,
+		MinOccurrences:   1,
+	}
+}
+
+// Returns the transitions of the longest suffix of state satisfying the thresholds.
+// The context of a single token is used regardless of the thresholds.
+// Longer contexts without continuations, e.g. left by pruning, are skipped even without thresholds.
+func findBackoffTransitions(src transitionSource, state []string, opts BackoffOptions) *transitions {
+	for order := len(state); order > 1; order-- {
+		t := src.lookup(state[len(state)-order:])
+		if t == nil || len(t.items) == 0 {
+			continue
+		}
+		if opts.MinContinuations <= len(t.items) && float64(opts.MinOccurrences) <= t.total() {
+			return t
+		}
+	}
+	return src.lookup(state[len(state)-1:])
+}
@@ -0,0 +1,102 @@
+package markov_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/paralleltree/markov-bot-go/markov"
+)
+
+func TestChain_GenerateWithBackoff_WithSingleSentence_Returns_Same_Sentence(t *testing.T) {
+	// arrange
+	chain := markov.NewChain(3, markov.WithVariableOrder())
+	chain.AddSource([]string{"A", "B", "C"})
+
+	// act
+	gotResult := chain.GenerateWithBackoff(markov.DefaultBackoffOptions())
+
+	// assert
+	wantResult := []string{"A", "B", "C"}
+	if !slices.Equal(wantResult, gotResult) {
+		t.Fatalf("unexpected result: want %v, but got %v", wantResult, gotResult)
+	}
+}
+
+func TestChain_GenerateWithBackoff_BacksOffToShorterContext(t *testing.T) {
+	// arrange
+	stateSize := 3
+	sources := [][]string{
+		{"A", "B", "C", "D"},
+		{"X", "B", "C", "Y"},
+	}
+	fixedChain := markov.NewChain(stateSize)
+	variableChain := markov.NewChain(stateSize, markov.WithVariableOrder())
+	for _, source := range sources {
+		fixedChain.AddSource(source)
+		variableChain.AddSource(source)
+	}
+	novelResult := []string{"A", "B", "C", "Y"}
+
+	// act
+	fixedResults := [][]string{}
+	variableResults := [][]string{}
+	for i := 0; i < 200; i++ {
+		fixedResults = append(fixedResults, fixedChain.GenerateWithBackoff(markov.DefaultBackoffOptions()))
+		variableResults = append(variableResults, variableChain.GenerateWithBackoff(markov.DefaultBackoffOptions()))
+	}
+
+	// assert
+	containsResult := func(results [][]string, want []string) bool {
+		return slices.ContainsFunc(results, func(v []string) bool { return slices.Equal(want, v) })
+	}
+	if containsResult(fixedResults, novelResult) {
+		t.Fatalf("fixed-order chain should not generate %v", novelResult)
+	}
+	if !containsResult(variableResults, novelResult) {
+		t.Fatalf("variable-order chain should generate %v by backing off, but got %v", novelResult, variableResults)
+	}
+}
+
+func TestChain_GenerateWithBackoff_WithMinimumThresholds_UsesLongestContext(t *testing.T) {
+	// arrange
+	chain := markov.NewChain(3, markov.WithVariableOrder())
+	chain.AddSource([]string{"A", "B", "C", "D"})
+	chain.AddSource([]string{"X", "B", "C", "Y"})
+	opts := markov.BackoffOptions{MinContinuations: 1, MinOccurrences: 1}
+
+	// act & assert
+	for i := 0; i < 100; i++ {
+		got := chain.GenerateWithBackoff(opts)
+		if !slices.Equal([]string{"A", "B", "C", "D"}, got) && !slices.Equal([]string{"X", "B", "C", "Y"}, got) {
+			t.Fatalf("unexpected result: %v", got)
+		}
+	}
+}
+
+func TestChain_GenerateWithBackoff_DoesNotCountChildrenWithoutOccurrencesAsContinuations(t *testing.T) {
+	// arrange
+	// the context [Q P] is followed only by "a", and "z" is a child without occurrences like prefixes of longer contexts
+	data := `{"state_size":2,"variable_order":true,"root_node":{"occurences":0,"children":{` +
+		`"__BOS__":{"occurences":0,"children":{"__BOS__":{"occurences":0,"children":{"Q":{"occurences":1,"children":{}}}},"Q":{"occurences":1,"children":{"P":{"occurences":1,"children":{}}}}}},` +
+		`"Q":{"occurences":1,"children":{"P":{"occurences":1,"children":{"a":{"occurences":1,"children":{}},"z":{"occurences":0,"children":{}}}}}},` +
+		`"P":{"occurences":2,"children":{"a":{"occurences":1,"children":{}},"b":{"occurences":1,"children":{}}}},` +
+		`"a":{"occurences":1,"children":{"__EOS__":{"occurences":1,"children":{}}}},` +
+		`"b":{"occurences":1,"children":{"__EOS__":{"occurences":1,"children":{}}}}}}}`
+	chain, err := markov.LoadChain([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	opts := markov.BackoffOptions{MinContinuations: 2, MinOccurrences: 1}
+
+	// act
+	results := [][]string{}
+	for i := 0; i < 200; i++ {
+		results = append(results, chain.GenerateWithBackoff(opts))
+	}
+
+	// assert
+	want := []string{"Q", "P", "b"}
+	if !slices.ContainsFunc(results, func(v []string) bool { return slices.Equal(want, v) }) {
+		t.Fatalf("chain should back off from [Q P] to generate %v, but got %v", want, results)
+	}
+}
@@ -1,10 +1,10 @@
 package markov
 
 import (
+	"bytes"
 	"encoding/json"
-	"fmt"
-	"math/rand"
 	"sort"
+	"sync"
 )
 
 const (
@@ -12,21 +12,77 @@ const (
 	EOS = "__EOS__"
 )
 
+// Versions of the model format.
+const (
+	// Tokens do not carry whitespaces, so spaces between words are guessed on joining.
+	LegacyFormatVersion = 0
+	// Tokens carry trailing whitespaces as they appeared in the source.
+	WhitespacePreservingFormatVersion = 1
+
+	CurrentFormatVersion = WhitespacePreservingFormatVersion
+)
+
+type TokenUnit string
+
+const (
+	// Tokens are words given by a morpheme analyzer.
+	UnitWord TokenUnit = "word"
+	// Tokens are characters, and StateSize means the number of characters.
+	UnitCharacter TokenUnit = "character"
+)
+
+// Chain is safe for concurrent use: sources can be added while other goroutines generate sequences.
 type Chain struct {
-	StateSize int        `json:"state_size"`
-	RootNode  *chainNode `json:"root_node"`
+	mu sync.RWMutex
+
+	Version   int       `json:"version,omitempty"`
+	Unit      TokenUnit `json:"unit,omitempty"`
+	StateSize int       `json:"state_size"`
+	// If true, the chain stores occurrences of all orders from 1 to StateSize,
+	// and the occurrences of a node are the count of the sequence from the root to the node.
+	VariableOrder bool `json:"variable_order,omitempty"`
+	// Describes how this chain was built. Nil for chains built without metadata.
+	Metadata *Metadata  `json:"metadata,omitempty"`
+	RootNode *chainNode `json:"root_node"`
+	// Tokens of the sentences in Sources, each recorded once and referred by its index to keep the model compact.
+	SourceTokens []string `json:"source_tokens,omitempty"`
+	// Sentences added by each source post, keyed by post ID, to forget the post later.
+	// Nil unless sources are added with AddAttributedSource.
+	Sources map[string][]Contribution `json:"sources,omitempty"`
+	// Indices of SourceTokens, built when a source is added.
+	sourceTokenIndices map[string]int
 }
 
-func NewChain(stateSize int) *Chain {
-	return &Chain{
+func NewChain(stateSize int, optFns ...func(*Chain)) *Chain {
+	c := &Chain{
+		Version:   CurrentFormatVersion,
+		Unit:      UnitWord,
 		StateSize: stateSize,
 		RootNode:  newChainNode(),
 	}
+	for _, f := range optFns {
+		f(c)
+	}
+	return c
+}
+
+func WithUnit(unit TokenUnit) func(c *Chain) {
+	return func(c *Chain) {
+		c.Unit = unit
+	}
+}
+
+// Makes the chain store contexts shorter than the state size to back off on generation.
+func WithVariableOrder() func(c *Chain) {
+	return func(c *Chain) {
+		c.VariableOrder = true
+	}
 }
 
 type chainNode struct {
-	Children    map[string]*chainNode `json:"children"`
-	Occurrences int                   `json:"occurences"`
+	Children map[string]*chainNode `json:"children"`
+	// The sum of weights of the sources containing the sequence. Each source has the weight of 1 by default.
+	Occurrences float64 `json:"occurences"`
 }
 
 func newChainNode() *chainNode {
@@ -35,6 +91,19 @@ func newChainNode() *chainNode {
 
 // Adds single source to this chain.
 func (c *Chain) AddSource(source []string) {
+	c.AddWeightedSource(source, 1)
+}
+
+// Adds single source to this chain, counting each of its transitions as weight occurrences.
+// Fractional weights make some sources less influential than others.
+func (c *Chain) AddWeightedSource(source []string, weight float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.addSource(source, weight)
+}
+
+func (c *Chain) addSource(source []string, weight float64) {
 	// fill BOS/EOS
 	run := makeRun(c.StateSize, source)
 
@@ -43,6 +112,11 @@ func (c *Chain) AddSource(source []string) {
 		return
 	}
 
+	if c.VariableOrder {
+		c.addVariableOrderRun(run, weight)
+		return
+	}
+
 	for i := 0; i < len(run)-c.StateSize; i++ {
 		tailNode := c.findOrAddTailNode(run[i : i+c.StateSize])
 		// find or add leaf node
@@ -53,31 +127,57 @@ func (c *Chain) AddSource(source []string) {
 			tailNode.Children[nextElement] = followNode
 		}
 		// increment occurrence at leaf node
-		followNode.Occurrences += 1
+		followNode.Occurrences += weight
+	}
+}
+
+// Counts every n-gram ending at each token for orders from 1 to StateSize.
+func (c *Chain) addVariableOrderRun(run []string, weight float64) {
+	for i := c.StateSize; i < len(run); i++ {
+		for order := 0; order <= c.StateSize; order++ {
+			c.findOrAddTailNode(run[i-order : i+1]).Occurrences += weight
+		}
 	}
 }
 
 // Generates the sequence from this chain.
 func (c *Chain) Generate() []string {
-	buf := make([]string, c.StateSize)
-	for i := 0; i < c.StateSize; i++ {
-		buf[i] = BOS
-	}
-	for i := 0; ; i++ {
-		tailNode := c.findOrAddTailNode(buf[i : i+c.StateSize])
-		items, cumsum := tailNode.accumulateOccurrences()
-		if len(items) == 0 {
-			// no words found in this Chain
+	return c.GenerateWithOptions()
+}
+
+// Generates the sequence predicting each token from the longest context satisfying the thresholds.
+// If the chain is not built with variable order, this is same as Generate.
+func (c *Chain) GenerateWithBackoff(opts BackoffOptions) []string {
+	return c.GenerateWithOptions(WithBackoff(opts))
+}
+
+// Generates the sequence with given options.
+func (c *Chain) GenerateWithOptions(optFns ...func(*generateConf)) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return generate(c, c.StateSize, c.VariableOrder, newGenerateConf(optFns...))
+}
+
+// Returns the transitions from the state, or nil if the state is not found.
+func (c *Chain) lookup(state []string) *transitions {
+	node := c.findTailNode(state)
+	if node == nil {
+		return nil
+	}
+	return newTransitions(node.listChildren())
+}
+
+// Find tail node that is last of state sequence. Returns nil if not found.
+func (c *Chain) findTailNode(state []string) *chainNode {
+	tailNode := c.RootNode
+	for i := 0; i < len(state); i++ {
+		nextNode, ok := tailNode.Children[state[i]]
+		if !ok {
 			return nil
 		}
-		r := rand.Intn(cumsum[len(cumsum)-1])
-		elected := items[sort.SearchInts(cumsum, r)]
-		if elected == EOS {
-			break
-		}
-		buf = append(buf, elected)
+		tailNode = nextNode
 	}
-	return buf[c.StateSize:]
+	return tailNode
 }
 
 // Find or add tail node that is last of state sequence
@@ -94,29 +194,38 @@ func (c *Chain) findOrAddTailNode(state []string) *chainNode {
 	return tailNode
 }
 
-// Returns cumulative sum and its value
-func (n *chainNode) accumulateOccurrences() ([]string, []int) {
-	sum := 0
-	accum := make([]int, 0, len(n.Children))
+// Returns children and their occurrences in order of keys,
+// so that the result of sampling with a seeded random source does not depend on the order of map iteration.
+func (n *chainNode) listChildren() ([]string, []float64) {
 	values := make([]string, 0, len(n.Children))
-	for k, v := range n.Children {
-		sum += v.Occurrences
+	for k := range n.Children {
 		values = append(values, k)
-		accum = append(accum, sum)
 	}
-	return values, accum
+	sort.Strings(values)
+	occurrences := make([]float64, len(values))
+	for i, k := range values {
+		occurrences[i] = n.Children[k].Occurrences
+	}
+	return values, occurrences
 }
 
 func (c *Chain) Dump() ([]byte, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return json.Marshal(c)
 }
 
 func LoadChain(s []byte) (*Chain, error) {
-	c := &Chain{}
-	if err := json.Unmarshal(s, c); err != nil {
-		return nil, fmt.Errorf("unmarshal chain: %w", err)
+	return ReadChain(bytes.NewReader(s))
+}
+
+// Returns the initial state that contains [BOS * stateSize]
+func bosState(stateSize int) []string {
+	state := make([]string, stateSize)
+	for i := range state {
+		state[i] = BOS
 	}
-	return c, nil
+	return state
 }
 
 // Makes string slice that contains [BOS * stateSize, source, EOS]
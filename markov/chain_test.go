@@ -1,8 +1,10 @@
 package markov_test
 
 import (
+	"math"
 	"reflect"
 	"slices"
+	"sync"
 	"testing"
 
 	"github.com/paralleltree/markov-bot-go/markov"
@@ -62,3 +64,72 @@ func TestChain_DumpAndLoad_Restores_Model(t *testing.T) {
 		t.Fatalf("unexpected result: original: %v, but restored: %v", originalChain, restoredChain)
 	}
 }
+
+func TestChain_AddSource_IsSafeForConcurrentUse(t *testing.T) {
+	// arrange
+	sources := [][]string{}
+	for i := 0; i < 100; i++ {
+		sources = append(sources, []string{"A", string(rune('a' + i%26)), "B", string(rune('a' + i%7))})
+	}
+	sequentialChain := markov.NewChain(2)
+	for _, source := range sources {
+		sequentialChain.AddSource(source)
+	}
+
+	// act
+	concurrentChain := markov.NewChain(2)
+	var wg sync.WaitGroup
+	for _, source := range sources {
+		wg.Add(1)
+		go func(source []string) {
+			defer wg.Done()
+			concurrentChain.AddSource(source)
+			concurrentChain.Generate()
+		}(source)
+	}
+	wg.Wait()
+
+	// assert
+	want, err := sequentialChain.Dump()
+	if err != nil {
+		t.Fatalf("unexpected error while dumping chain: %v", err)
+	}
+	got, err := concurrentChain.Dump()
+	if err != nil {
+		t.Fatalf("unexpected error while dumping chain: %v", err)
+	}
+	if string(want) != string(got) {
+		t.Fatalf("unexpected result: want %s, but got %s", want, got)
+	}
+}
+
+func TestChain_AddWeightedSource_WeighsTransitions(t *testing.T) {
+	// arrange
+	chain := markov.NewChain(1)
+
+	// act
+	chain.AddWeightedSource([]string{"A", "B"}, 1.5)
+	chain.AddWeightedSource([]string{"A", "C"}, 0.5)
+
+	// assert
+	if got, want := chain.LogProbability([]string{"A", "B"}), math.Log(0.75); math.Abs(want-got) > 1e-9 {
+		t.Fatalf("unexpected log probability: want %v, but got %v", want, got)
+	}
+}
+
+func TestLoadChain_WithIntegerOccurrences_LoadsModel(t *testing.T) {
+	// arrange
+	data := `{"state_size":1,"root_node":{"children":{"__BOS__":{"children":{"A":{"children":{},"occurences":2}},"occurences":0},` +
+		`"A":{"children":{"__EOS__":{"children":{},"occurences":2}},"occurences":0}},"occurences":0}}`
+
+	// act
+	chain, err := markov.LoadChain([]byte(data))
+
+	// assert
+	if err != nil {
+		t.Fatalf("unexpected error while loading chain: %v", err)
+	}
+	if got := chain.Generate(); !reflect.DeepEqual([]string{"A"}, got) {
+		t.Fatalf("unexpected result: %v", got)
+	}
+}
@@ -0,0 +1,40 @@
+package markov
+
+import (
+	"regexp"
+	"strings"
+)
+
+var legacyWordPattern = regexp.MustCompile(`^[A-Za-z]+$`)
+
+// Joins generated tokens into a text.
+// Tokens carry their trailing whitespaces, so they are concatenated as is.
+// Characters are joined without separators.
+// For word models built before whitespaces were preserved, a space is inserted between alphabetical words.
+func (c *Chain) Join(tokens []string) string {
+	return join(c.Version, c.Unit, tokens)
+}
+
+func join(version int, unit TokenUnit, tokens []string) string {
+	if unit == UnitCharacter || version >= WhitespacePreservingFormatVersion {
+		return strings.Join(tokens, "")
+	}
+	return joinLegacyTokens(tokens)
+}
+
+func joinLegacyTokens(tokens []string) string {
+	if len(tokens) < 1 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(tokens[0])
+	for i, word := range tokens[1:] {
+		prev := tokens[i]
+		if legacyWordPattern.MatchString(prev) && legacyWordPattern.MatchString(word) {
+			b.WriteString(" ")
+		}
+		b.WriteString(word)
+	}
+	return b.String()
+}
@@ -0,0 +1,40 @@
+package markov_test
+
+import (
+	"testing"
+
+	"github.com/paralleltree/markov-bot-go/markov"
+)
+
+func TestChain_Join_JoinsTokensCarryingSpaces(t *testing.T) {
+	// arrange
+	chain := markov.NewChain(2)
+	tokens := []string{"I ", "don't ", "know", ", ", "e.g. ", "12", ":", "00", "の", "ログ"}
+
+	// act
+	got := chain.Join(tokens)
+
+	// assert
+	want := "I don't know, e.g. 12:00のログ"
+	if want != got {
+		t.Fatalf("unexpected result: want %q, but got %q", want, got)
+	}
+}
+
+func TestChain_Join_WithLegacyModel_InsertsSpacesBetweenWords(t *testing.T) {
+	// arrange
+	chain, err := markov.LoadChain([]byte(`{"state_size":2,"root_node":{"children":{},"occurences":0}}`))
+	if err != nil {
+		t.Fatalf("unexpected error while loading chain: %v", err)
+	}
+	tokens := []string{"The", "quick", "fox", "の", "EZ", "DO"}
+
+	// act
+	got := chain.Join(tokens)
+
+	// assert
+	want := "The quick foxのEZ DO"
+	if want != got {
+		t.Fatalf("unexpected result: want %q, but got %q", want, got)
+	}
+}
@@ -0,0 +1,266 @@
+package markov
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Header keys of the TSV format.
+const (
+	tsvHeaderVersion       = "version"
+	tsvHeaderUnit          = "unit"
+	tsvHeaderStateSize     = "state_size"
+	tsvHeaderVariableOrder = "variable_order"
+	tsvHeaderMetadata      = "metadata"
+)
+
+// Writes every transition of this chain as a line of "state<TAB>next<TAB>count".
+// Sources recorded for provenance are not written.
+// Counts are written as integers unless sources are weighted fractionally.
+// Tokens are quoted as Go string literals so that whitespaces in tokens are visible,
+// and tokens of the state are separated by a space.
+// Lines are sorted by state and next token, so that exported chains can be compared with diff.
+// Parameters of the chain are written as header lines starting with "#".
+//
+// In variable-order chains, every context shorter than the state size is also written with its count.
+func (c *Chain) WriteTSV(w io.Writer) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# %s=%d\n", tsvHeaderVersion, c.Version)
+	fmt.Fprintf(bw, "# %s=%s\n", tsvHeaderUnit, c.Unit)
+	fmt.Fprintf(bw, "# %s=%d\n", tsvHeaderStateSize, c.StateSize)
+	fmt.Fprintf(bw, "# %s=%t\n", tsvHeaderVariableOrder, c.VariableOrder)
+	if c.Metadata != nil {
+		metadata, err := json.Marshal(c.Metadata)
+		if err != nil {
+			return fmt.Errorf("marshal metadata: %w", err)
+		}
+		fmt.Fprintf(bw, "# %s=%s\n", tsvHeaderMetadata, metadata)
+	}
+
+	walkTransitions(c.RootNode, []string{}, func(path []string, occurrences float64) {
+		fmt.Fprintf(bw, "%s\t%s\t%s\n", quoteTokens(path[:len(path)-1]), strconv.Quote(path[len(path)-1]), formatOccurrences(occurrences))
+	})
+	return bw.Flush()
+}
+
+// Reads a chain written by WriteTSV.
+// Transitions can be removed or edited by hand; counts of the other transitions are kept as they are.
+func ReadTSV(r io.Reader) (*Chain, error) {
+	c := NewChain(0)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			if err := c.readTSVHeader(strings.TrimSpace(line[1:])); err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			continue
+		}
+		path, occurrences, err := parseTSVTransition(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		c.findOrAddTailNode(path).Occurrences = occurrences
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read tsv: %w", err)
+	}
+	if c.StateSize <= 0 {
+		return nil, fmt.Errorf("%s is not specified", tsvHeaderStateSize)
+	}
+	return c, nil
+}
+
+func (c *Chain) readTSVHeader(header string) error {
+	key, value, ok := strings.Cut(header, "=")
+	if !ok {
+		// comments
+		return nil
+	}
+	var err error
+	switch key {
+	case tsvHeaderVersion:
+		c.Version, err = strconv.Atoi(value)
+	case tsvHeaderUnit:
+		c.Unit = TokenUnit(value)
+	case tsvHeaderStateSize:
+		c.StateSize, err = strconv.Atoi(value)
+	case tsvHeaderVariableOrder:
+		c.VariableOrder, err = strconv.ParseBool(value)
+	case tsvHeaderMetadata:
+		c.Metadata = &Metadata{}
+		err = json.Unmarshal([]byte(value), c.Metadata)
+	}
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", key, err)
+	}
+	return nil
+}
+
+func parseTSVTransition(line string) ([]string, float64, error) {
+	fields := strings.Split(line, "\t")
+	if len(fields) != 3 {
+		return nil, 0, fmt.Errorf("want 3 fields, but got %d", len(fields))
+	}
+	path, err := unquoteTokens(fields[0])
+	if err != nil {
+		return nil, 0, fmt.Errorf("parse state: %w", err)
+	}
+	next, err := strconv.Unquote(fields[1])
+	if err != nil {
+		return nil, 0, fmt.Errorf("parse next token: %w", err)
+	}
+	occurrences, err := strconv.ParseFloat(fields[2], 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("parse count: %w", err)
+	}
+	if !(0 < occurrences) || math.IsInf(occurrences, 1) {
+		return nil, 0, fmt.Errorf("count must be positive and finite, but got %s", fields[2])
+	}
+	return append(path, next), occurrences, nil
+}
+
+// Writes the chain as canonical JSON: keys are sorted and indented, so that dumped chains can be compared with diff.
+func (c *Chain) WriteJSON(w io.Writer) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(c)
+}
+
+// Writes a Graphviz DOT graph of tokens within depth transitions before or after the token.
+// Each edge is labeled with the total count of the transition between two tokens regardless of the preceding context.
+func (c *Chain) WriteDOT(w io.Writer, token string, depth int) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	edges := c.tokenEdges()
+	neighbors := map[string][]string{}
+	for e := range edges {
+		neighbors[e.from] = append(neighbors[e.from], e.to)
+		neighbors[e.to] = append(neighbors[e.to], e.from)
+	}
+
+	// find tokens within depth hops in either direction
+	visited := map[string]bool{token: true}
+	frontier := []string{token}
+	for i := 0; i < depth; i++ {
+		next := []string{}
+		for _, t := range frontier {
+			for _, n := range neighbors[t] {
+				if !visited[n] {
+					visited[n] = true
+					next = append(next, n)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	included := []tokenEdge{}
+	for e := range edges {
+		if visited[e.from] && visited[e.to] {
+			included = append(included, e)
+		}
+	}
+	sort.Slice(included, func(i, j int) bool {
+		if included[i].from != included[j].from {
+			return included[i].from < included[j].from
+		}
+		return included[i].to < included[j].to
+	})
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph chain {")
+	fmt.Fprintf(bw, "  %s [style=bold];\n", strconv.Quote(token))
+	for _, e := range included {
+		fmt.Fprintf(bw, "  %s -> %s [label=\"%s\"];\n", strconv.Quote(e.from), strconv.Quote(e.to), formatOccurrences(edges[e]))
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+type tokenEdge struct {
+	from, to string
+}
+
+// Sums counts of transitions by the last token of the state and the next token.
+func (c *Chain) tokenEdges() map[tokenEdge]float64 {
+	edges := map[tokenEdge]float64{}
+	if c.StateSize <= 0 {
+		return edges
+	}
+	// variable-order chains hold the counts of bigrams directly
+	depth := c.StateSize + 1
+	if c.VariableOrder {
+		depth = 2
+	}
+	walkTransitions(c.RootNode, []string{}, func(path []string, occurrences float64) {
+		if len(path) != depth {
+			return
+		}
+		edges[tokenEdge{from: path[len(path)-2], to: path[len(path)-1]}] += occurrences
+	})
+	return edges
+}
+
+// Calls f with the path to every node having occurrences, in order of tokens.
+func walkTransitions(node *chainNode, path []string, f func(path []string, occurrences float64)) {
+	keys, _ := node.listChildren()
+	for _, k := range keys {
+		child := node.Children[k]
+		childPath := append(path[:len(path):len(path)], k)
+		if child.Occurrences > 0 {
+			f(childPath, child.Occurrences)
+		}
+		walkTransitions(child, childPath, f)
+	}
+}
+
+// Formats occurrences in the shortest form which is parsed back to the same value.
+func formatOccurrences(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func quoteTokens(tokens []string) string {
+	quoted := make([]string, len(tokens))
+	for i, t := range tokens {
+		quoted[i] = strconv.Quote(t)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// Parses space separated quoted tokens.
+func unquoteTokens(s string) ([]string, error) {
+	tokens := []string{}
+	rest := strings.TrimSpace(s)
+	for rest != "" {
+		quoted, err := strconv.QuotedPrefix(rest)
+		if err != nil {
+			return nil, fmt.Errorf("unquote %s: %w", rest, err)
+		}
+		token, err := strconv.Unquote(quoted)
+		if err != nil {
+			return nil, fmt.Errorf("unquote %s: %w", quoted, err)
+		}
+		tokens = append(tokens, token)
+		rest = strings.TrimSpace(rest[len(quoted):])
+	}
+	return tokens, nil
+}
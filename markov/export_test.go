@@ -0,0 +1,159 @@
+package markov_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/paralleltree/markov-bot-go/markov"
+)
+
+func TestChain_WriteTSV_WritesSortedTransitions(t *testing.T) {
+	// arrange
+	chain := markov.NewChain(1)
+	chain.AddSource([]string{"b ", "a"})
+	chain.AddSource([]string{"b ", "a"})
+	want := strings.Join([]string{
+		"# version=1",
+		"# unit=word",
+		"# state_size=1",
+		"# variable_order=false",
+		`"__BOS__"` + "\t" + `"b "` + "\t2",
+		`"a"` + "\t" + `"__EOS__"` + "\t2",
+		`"b "` + "\t" + `"a"` + "\t2",
+		"",
+	}, "\n")
+	buf := &bytes.Buffer{}
+
+	// act
+	err := chain.WriteTSV(buf)
+
+	// assert
+	if err != nil {
+		t.Fatalf("WriteTSV() should not return error, but got: %v", err)
+	}
+	if want != buf.String() {
+		t.Fatalf("unexpected output: want %q, but got %q", want, buf.String())
+	}
+}
+
+func TestReadTSV_RestoresWrittenChain(t *testing.T) {
+	cases := []struct {
+		name  string
+		chain *markov.Chain
+	}{
+		{name: "fixed order", chain: markov.NewChain(2)},
+		{name: "variable order", chain: markov.NewChain(2, markov.WithVariableOrder())},
+		{name: "with metadata", chain: func() *markov.Chain {
+			c := markov.NewChain(2, markov.WithUnit(markov.UnitCharacter))
+			c.Metadata = &markov.Metadata{BotName: "bot", FetchedStatusesCount: 3}
+			return c
+		}()},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			tt.chain.AddSource([]string{"a\t", "b ", "\"c\""})
+			tt.chain.AddSource([]string{"a\t", "d\n"})
+			want, err := tt.chain.Dump()
+			if err != nil {
+				t.Fatalf("unexpected error while dumping chain: %v", err)
+			}
+			buf := &bytes.Buffer{}
+			if err := tt.chain.WriteTSV(buf); err != nil {
+				t.Fatalf("WriteTSV() should not return error, but got: %v", err)
+			}
+
+			// act
+			restored, err := markov.ReadTSV(buf)
+
+			// assert
+			if err != nil {
+				t.Fatalf("ReadTSV() should not return error, but got: %v", err)
+			}
+			got, err := restored.Dump()
+			if err != nil {
+				t.Fatalf("unexpected error while dumping chain: %v", err)
+			}
+			if string(want) != string(got) {
+				t.Fatalf("unexpected chain: want %s, but got %s", want, got)
+			}
+		})
+	}
+}
+
+func TestReadTSV_WithRemovedTransition_DoesNotGenerateIt(t *testing.T) {
+	// arrange
+	input := strings.Join([]string{
+		"# state_size=1",
+		`"__BOS__"` + "\t" + `"a"` + "\t1",
+		`"a"` + "\t" + `"__EOS__"` + "\t1",
+	}, "\n")
+
+	// act
+	chain, err := markov.ReadTSV(strings.NewReader(input))
+
+	// assert
+	if err != nil {
+		t.Fatalf("ReadTSV() should not return error, but got: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if got := chain.Generate(); len(got) != 1 || got[0] != "a" {
+			t.Fatalf("unexpected sequence: %q", got)
+		}
+	}
+}
+
+func TestReadTSV_WithInvalidInput_ReturnsError(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+	}{
+		{name: "missing state size", input: `"a"` + "\t" + `"b"` + "\t1"},
+		{name: "missing field", input: "# state_size=1\n" + `"a"` + "\t1"},
+		{name: "unquoted token", input: "# state_size=1\na\t\"b\"\t1"},
+		{name: "invalid count", input: "# state_size=1\n\"a\"\t\"b\"\tx"},
+		{name: "zero count", input: "# state_size=1\n\"a\"\t\"b\"\t0"},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			_, err := markov.ReadTSV(strings.NewReader(tt.input))
+
+			// assert
+			if err == nil {
+				t.Fatalf("ReadTSV() should return error, but got nil")
+			}
+		})
+	}
+}
+
+func TestChain_WriteDOT_WritesNeighborhoodOfToken(t *testing.T) {
+	// arrange
+	chain := markov.NewChain(2)
+	chain.AddSource([]string{"a", "b", "c", "d"})
+	chain.AddSource([]string{"x", "b", "c"})
+	want := strings.Join([]string{
+		"digraph chain {",
+		`  "b" [style=bold];`,
+		`  "a" -> "b" [label="1"];`,
+		`  "b" -> "c" [label="2"];`,
+		`  "x" -> "b" [label="1"];`,
+		"}",
+		"",
+	}, "\n")
+	buf := &bytes.Buffer{}
+
+	// act
+	err := chain.WriteDOT(buf, "b", 1)
+
+	// assert
+	if err != nil {
+		t.Fatalf("WriteDOT() should not return error, but got: %v", err)
+	}
+	if want != buf.String() {
+		t.Fatalf("unexpected output: want %q, but got %q", want, buf.String())
+	}
+}
@@ -0,0 +1,96 @@
+package markov
+
+import "sort"
+
+// FrozenChain is an immutable representation of Chain optimized for generation.
+// Transitions of every state are sorted and their cumulative sums are precomputed,
+// so generating does not walk or allocate candidates on each step.
+// It is safe for concurrent use by multiple goroutines, given each goroutine uses its own random source.
+type FrozenChain struct {
+	Version       int
+	Unit          TokenUnit
+	StateSize     int
+	VariableOrder bool
+	root          *frozenNode
+}
+
+type frozenNode struct {
+	children    map[string]*frozenNode
+	transitions *transitions
+}
+
+// Returns a frozen copy of this chain. Later changes to this chain are not reflected to the copy.
+func (c *Chain) Freeze() *FrozenChain {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return &FrozenChain{
+		Version:       c.Version,
+		Unit:          c.Unit,
+		StateSize:     c.StateSize,
+		VariableOrder: c.VariableOrder,
+		root:          freezeNode(c.RootNode),
+	}
+}
+
+func freezeNode(n *chainNode) *frozenNode {
+	items, occurrences := n.listChildren()
+	children := make(map[string]*frozenNode, len(items))
+	for _, k := range items {
+		children[k] = freezeNode(n.Children[k])
+	}
+	return &frozenNode{
+		children:    children,
+		transitions: newTransitions(items, occurrences),
+	}
+}
+
+// Generates the sequence from this chain.
+func (c *FrozenChain) Generate() []string {
+	return c.GenerateWithOptions()
+}
+
+// Generates the sequence with given options.
+func (c *FrozenChain) GenerateWithOptions(optFns ...func(*generateConf)) []string {
+	return generate(c, c.StateSize, c.VariableOrder, newGenerateConf(optFns...))
+}
+
+// Joins generated tokens into a text. See Chain.Join for details.
+func (c *FrozenChain) Join(tokens []string) string {
+	return join(c.Version, c.Unit, tokens)
+}
+
+// Returns the tokens which can be generated, i.e. follow any state, in order.
+func (c *FrozenChain) Tokens() []string {
+	seen := map[string]bool{}
+	tokens := []string{}
+	var walk func(node *frozenNode, depth int)
+	walk = func(node *frozenNode, depth int) {
+		if depth > 0 {
+			for _, child := range node.children {
+				walk(child, depth-1)
+			}
+			return
+		}
+		for _, token := range node.transitions.items {
+			if token != EOS && !seen[token] {
+				seen[token] = true
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	walk(c.root, c.StateSize)
+	sort.Strings(tokens)
+	return tokens
+}
+
+func (c *FrozenChain) lookup(state []string) *transitions {
+	node := c.root
+	for _, v := range state {
+		next, ok := node.children[v]
+		if !ok {
+			return nil
+		}
+		node = next
+	}
+	return node.transitions
+}
@@ -0,0 +1,179 @@
+package markov_test
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/paralleltree/markov-bot-go/markov"
+)
+
+// Builds a chain from random sentences generated with a fixed seed.
+func buildRandomChain(stateSize int, sentencesCount int, optFns ...func(*markov.Chain)) *markov.Chain {
+	r := rand.New(rand.NewSource(1))
+	chain := markov.NewChain(stateSize, optFns...)
+	for i := 0; i < sentencesCount; i++ {
+		sentence := make([]string, 5+r.Intn(20))
+		for j := range sentence {
+			// skewed distribution of words
+			sentence[j] = fmt.Sprintf("w%d", r.Intn(1+r.Intn(500)))
+		}
+		chain.AddSource(sentence)
+	}
+	return chain
+}
+
+func TestFrozenChain_GenerateWithOptions_ReturnsSameSequenceAsChain(t *testing.T) {
+	cases := []struct {
+		name  string
+		chain *markov.Chain
+	}{
+		{
+			name:  "fixed order",
+			chain: buildRandomChain(2, 100),
+		},
+		{
+			name:  "variable order",
+			chain: buildRandomChain(3, 100, markov.WithVariableOrder()),
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			frozen := tt.chain.Freeze()
+
+			for seed := int64(0); seed < 20; seed++ {
+				// act
+				want := tt.chain.GenerateWithOptions(markov.WithRand(rand.New(rand.NewSource(seed))), markov.WithBackoff(markov.DefaultBackoffOptions()))
+				got := frozen.GenerateWithOptions(markov.WithRand(rand.New(rand.NewSource(seed))), markov.WithBackoff(markov.DefaultBackoffOptions()))
+
+				// assert
+				if !slices.Equal(want, got) {
+					t.Fatalf("unexpected result with seed %d: want %v, but got %v", seed, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestFrozenChain_IsNotAffectedByChangesToChain(t *testing.T) {
+	// arrange
+	chain := markov.NewChain(2)
+	chain.AddSource([]string{"A", "B", "C"})
+	frozen := chain.Freeze()
+
+	// act
+	chain.AddSource([]string{"X", "Y", "Z"})
+
+	// assert
+	for i := 0; i < 50; i++ {
+		if got := frozen.Generate(); !slices.Equal([]string{"A", "B", "C"}, got) {
+			t.Fatalf("unexpected result: %v", got)
+		}
+	}
+}
+
+func TestFrozenChain_Generate_IsSafeForConcurrentUse(t *testing.T) {
+	// arrange
+	frozen := buildRandomChain(2, 100).Freeze()
+	want := frozen.GenerateWithOptions(markov.WithRand(rand.New(rand.NewSource(1))))
+
+	// act
+	var wg sync.WaitGroup
+	results := make([][]string, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				frozen.Generate()
+			}
+			results[i] = frozen.GenerateWithOptions(markov.WithRand(rand.New(rand.NewSource(1))))
+		}(i)
+	}
+	wg.Wait()
+
+	// assert
+	for _, got := range results {
+		if !slices.Equal(want, got) {
+			t.Fatalf("unexpected result: want %v, but got %v", want, got)
+		}
+	}
+}
+
+func BenchmarkChain_Generate(b *testing.B) {
+	chain := buildRandomChain(2, 2000)
+	r := rand.New(rand.NewSource(1))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		chain.GenerateWithOptions(markov.WithRand(r))
+	}
+}
+
+func BenchmarkFrozenChain_Generate(b *testing.B) {
+	frozen := buildRandomChain(2, 2000).Freeze()
+	r := rand.New(rand.NewSource(1))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		frozen.GenerateWithOptions(markov.WithRand(r))
+	}
+}
+
+func BenchmarkFrozenChain_GenerateWithBackoff(b *testing.B) {
+	frozen := buildRandomChain(3, 2000, markov.WithVariableOrder()).Freeze()
+	r := rand.New(rand.NewSource(1))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		frozen.GenerateWithOptions(markov.WithRand(r), markov.WithBackoff(markov.DefaultBackoffOptions()))
+	}
+}
+
+func BenchmarkFrozenChain_Generate_Parallel(b *testing.B) {
+	frozen := buildRandomChain(2, 2000).Freeze()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		r := rand.New(rand.NewSource(1))
+		for pb.Next() {
+			frozen.GenerateWithOptions(markov.WithRand(r))
+		}
+	})
+}
+
+func BenchmarkChain_Freeze(b *testing.B) {
+	chain := buildRandomChain(2, 2000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		chain.Freeze()
+	}
+}
+
+func TestFrozenChain_Tokens_ReturnsGeneratableTokens(t *testing.T) {
+	for _, variableOrder := range []bool{false, true} {
+		t.Run(fmt.Sprintf("variable order %v", variableOrder), func(t *testing.T) {
+			// arrange
+			chainOpts := []func(*markov.Chain){}
+			if variableOrder {
+				chainOpts = append(chainOpts, markov.WithVariableOrder())
+			}
+			chain := markov.NewChain(2, chainOpts...)
+			chain.AddSource([]string{"b", "a", "c"})
+			chain.AddSource([]string{"a", "d"})
+
+			// act
+			got := chain.Freeze().Tokens()
+
+			// assert
+			if want := []string{"a", "b", "c", "d"}; !slices.Equal(want, got) {
+				t.Fatalf("unexpected tokens: want %q, but got %q", want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package markov
+
+import "math/rand"
+
+type generateConf struct {
+	sampling SamplingOptions
+	backoff  *BackoffOptions
+	rand     *rand.Rand
+}
+
+func newGenerateConf(optFns ...func(*generateConf)) *generateConf {
+	conf := &generateConf{
+		sampling: DefaultSamplingOptions(),
+	}
+	for _, f := range optFns {
+		f(conf)
+	}
+	return conf
+}
+
+// Sets the strategy to choose the next token.
+func WithSampling(opts SamplingOptions) func(c *generateConf) {
+	return func(c *generateConf) {
+		c.sampling = opts
+	}
+}
+
+// Backs off to shorter contexts with given thresholds. This takes effect on variable-order chains only.
+func WithBackoff(opts BackoffOptions) func(c *generateConf) {
+	return func(c *generateConf) {
+		c.backoff = &opts
+	}
+}
+
+// Sets the random source used for sampling. If not set, the global source of math/rand is used.
+func WithRand(r *rand.Rand) func(c *generateConf) {
+	return func(c *generateConf) {
+		c.rand = r
+	}
+}
+
+// Candidates of the next token sorted by token.
+type transitions struct {
+	items       []string
+	occurrences []float64
+	// cumulative sums of occurrences
+	cumsum []float64
+}
+
+// Candidates without occurrences are excluded, since they are prefixes of longer contexts,
+// e.g. BOS following BOS in variable-order chains, rather than transitions.
+func newTransitions(items []string, occurrences []float64) *transitions {
+	t := &transitions{
+		items:       make([]string, 0, len(items)),
+		occurrences: make([]float64, 0, len(occurrences)),
+		cumsum:      make([]float64, 0, len(occurrences)),
+	}
+	sum := 0.0
+	for i, v := range occurrences {
+		if v == 0 {
+			continue
+		}
+		sum += v
+		t.items = append(t.items, items[i])
+		t.occurrences = append(t.occurrences, v)
+		t.cumsum = append(t.cumsum, sum)
+	}
+	return t
+}
+
+// Returns the total occurrences of the candidates.
+func (t *transitions) total() float64 {
+	if len(t.cumsum) == 0 {
+		return 0
+	}
+	return t.cumsum[len(t.cumsum)-1]
+}
+
+type transitionSource interface {
+	// Returns the transitions from the state, or nil if the state is not found.
+	lookup(state []string) *transitions
+}
+
+// Generates the sequence choosing each token from the transitions of the current state.
+func generate(src transitionSource, stateSize int, variableOrder bool, conf *generateConf) []string {
+	lookup := src.lookup
+	if variableOrder && conf.backoff != nil {
+		lookup = func(state []string) *transitions {
+			return findBackoffTransitions(src, state, *conf.backoff)
+		}
+	}
+
+	buf := make([]string, stateSize, stateSize+16)
+	for i := 0; i < stateSize; i++ {
+		buf[i] = BOS
+	}
+	for i := 0; ; i++ {
+		t := lookup(buf[i : i+stateSize])
+		if t == nil || len(t.items) == 0 {
+			// no words found in this Chain
+			return nil
+		}
+		elected := t.items[conf.sampling.sample(conf.rand, t)]
+		if elected == EOS {
+			break
+		}
+		buf = append(buf, elected)
+	}
+	return buf[stateSize:]
+}
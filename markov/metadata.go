@@ -0,0 +1,36 @@
+package markov
+
+import (
+	"bytes"
+	"time"
+)
+
+// Metadata describes how a chain was built.
+// The state size and the format version are recorded in the chain itself.
+type Metadata struct {
+	BuiltAt time.Time `json:"built_at"`
+	// The name of the bot given in the configuration.
+	BotName string `json:"bot_name,omitempty"`
+	// The platform and the account the statuses were fetched from.
+	SourcePlatform string `json:"source_platform,omitempty"`
+	SourceAccount  string `json:"source_account,omitempty"`
+
+	FetchedStatusesCount int `json:"fetched_statuses_count"`
+	// The number of statuses which passed the filter and yielded at least one sentence.
+	KeptStatusesCount int `json:"kept_statuses_count"`
+	// IDs of the newest and the oldest learned statuses. Empty if the source does not provide IDs.
+	NewestStatusID string `json:"newest_status_id,omitempty"`
+	OldestStatusID string `json:"oldest_status_id,omitempty"`
+
+	Analyzer   string `json:"analyzer,omitempty"`
+	Dictionary string `json:"dictionary,omitempty"`
+
+	// The result of pruning. Nil if the chain was not pruned.
+	Pruning *PruneStats `json:"pruning,omitempty"`
+}
+
+// Reads only the metadata from a dumped chain without building its nodes.
+// Returns nil if the chain was built without metadata.
+func LoadMetadata(s []byte) (*Metadata, error) {
+	return ReadMetadata(bytes.NewReader(s))
+}
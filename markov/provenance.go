@@ -0,0 +1,161 @@
+package markov
+
+import (
+	"sort"
+)
+
+// Contribution is a sentence which a source post added to a chain with its weight.
+// The sentence is recorded as indices of Chain.SourceTokens.
+type Contribution struct {
+	Tokens []int   `json:"tokens"`
+	Weight float64 `json:"weight"`
+}
+
+// Adds single source to this chain like AddWeightedSource, and records that the post with the ID contributed it,
+// so that the post can be forgotten later.
+func (c *Chain) AddAttributedSource(postID string, source []string, weight float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.addSource(source, weight)
+	if len(source) == 0 {
+		return
+	}
+	if c.Sources == nil {
+		c.Sources = map[string][]Contribution{}
+	}
+	c.Sources[postID] = append(c.Sources[postID], Contribution{Tokens: c.indexSourceTokens(source), Weight: weight})
+}
+
+// Returns indices of the tokens in SourceTokens, adding tokens not recorded yet.
+func (c *Chain) indexSourceTokens(source []string) []int {
+	// the indices are not dumped, so they are rebuilt for loaded chains
+	if c.sourceTokenIndices == nil || len(c.sourceTokenIndices) != len(c.SourceTokens) {
+		c.sourceTokenIndices = make(map[string]int, len(c.SourceTokens))
+		for i, token := range c.SourceTokens {
+			c.sourceTokenIndices[token] = i
+		}
+	}
+	indices := make([]int, len(source))
+	for i, token := range source {
+		index, ok := c.sourceTokenIndices[token]
+		if !ok {
+			index = len(c.SourceTokens)
+			c.SourceTokens = append(c.SourceTokens, token)
+			c.sourceTokenIndices[token] = index
+		}
+		indices[i] = index
+	}
+	return indices
+}
+
+// Returns IDs of the posts recorded by AddAttributedSource in order.
+func (c *Chain) SourceIDs() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ids := make([]string, 0, len(c.Sources))
+	for id := range c.Sources {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Subtracts every contribution of the post from this chain, and removes transitions which are no longer seen.
+// Returns false if the post is not recorded.
+//
+// Transitions already removed by pruning are left as they are.
+// Since they can leave states without continuations once the post is subtracted,
+// dead ends are removed again as pruning does.
+// Tokens only the post referred to are removed from SourceTokens, so that they do not accumulate.
+func (c *Chain) Forget(postID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	contributions, ok := c.Sources[postID]
+	if !ok {
+		return false
+	}
+	for _, contribution := range contributions {
+		source := make([]string, len(contribution.Tokens))
+		for i, index := range contribution.Tokens {
+			source[i] = c.SourceTokens[index]
+		}
+		c.subtractSource(source, contribution.Weight)
+	}
+	delete(c.Sources, postID)
+	c.compactSourceTokens()
+	// transitions subtracted to zero are removed first, so that they do not count as continuations
+	removeEmptyNodes(c.RootNode)
+	c.removeDeadEnds()
+	removeEmptyNodes(c.RootNode)
+	return true
+}
+
+// Removes tokens no longer referred to by any source from SourceTokens, and renumbers the indices in Sources.
+func (c *Chain) compactSourceTokens() {
+	newIndices := make([]int, len(c.SourceTokens))
+	for i := range newIndices {
+		newIndices[i] = -1
+	}
+	for _, contributions := range c.Sources {
+		for _, contribution := range contributions {
+			for _, index := range contribution.Tokens {
+				newIndices[index] = 0
+			}
+		}
+	}
+	tokens := make([]string, 0, len(c.SourceTokens))
+	for i, token := range c.SourceTokens {
+		if newIndices[i] < 0 {
+			continue
+		}
+		newIndices[i] = len(tokens)
+		tokens = append(tokens, token)
+	}
+	if len(tokens) == len(c.SourceTokens) {
+		return
+	}
+
+	for _, contributions := range c.Sources {
+		for _, contribution := range contributions {
+			for i, index := range contribution.Tokens {
+				contribution.Tokens[i] = newIndices[index]
+			}
+		}
+	}
+	c.SourceTokens = tokens
+	// rebuilt when a source is added next time
+	c.sourceTokenIndices = nil
+}
+
+// Reverts addSource without adding nodes.
+func (c *Chain) subtractSource(source []string, weight float64) {
+	run := makeRun(c.StateSize, source)
+	if len(run) == c.StateSize+1 {
+		return
+	}
+
+	subtract := func(path []string) {
+		node := c.findTailNode(path)
+		if node == nil {
+			return
+		}
+		before := node.Occurrences
+		node.Occurrences -= weight
+		// treat rounding errors of weighted sums as zero
+		if node.Occurrences <= before*1e-12 {
+			node.Occurrences = 0
+		}
+	}
+	for i := c.StateSize; i < len(run); i++ {
+		if !c.VariableOrder {
+			subtract(run[i-c.StateSize : i+1])
+			continue
+		}
+		for order := 0; order <= c.StateSize; order++ {
+			subtract(run[i-order : i+1])
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package markov_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/paralleltree/markov-bot-go/markov"
+)
+
+func TestChain_Forget_RemovesContributionsOfPost(t *testing.T) {
+	cases := []struct {
+		name          string
+		variableOrder bool
+	}{
+		{name: "fixed order"},
+		{name: "variable order", variableOrder: true},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			chainOpts := []func(*markov.Chain){}
+			if tt.variableOrder {
+				chainOpts = append(chainOpts, markov.WithVariableOrder())
+			}
+			chain := markov.NewChain(2, chainOpts...)
+			chain.AddAttributedSource("1", []string{"a", "b", "c"}, 1)
+			chain.AddAttributedSource("2", []string{"a", "b", "d"}, 0.5)
+			chain.AddAttributedSource("2", []string{"e", "f"}, 0.5)
+			chain.AddAttributedSource("3", []string{"a", "c"}, 0.25)
+			want := markov.NewChain(2, chainOpts...)
+			want.AddWeightedSource([]string{"a", "b", "c"}, 1)
+			want.AddWeightedSource([]string{"a", "c"}, 0.25)
+
+			// act
+			ok := chain.Forget("2")
+
+			// assert
+			if !ok {
+				t.Fatalf("post is not forgotten")
+			}
+			if wantIDs, gotIDs := []string{"1", "3"}, chain.SourceIDs(); !reflect.DeepEqual(wantIDs, gotIDs) {
+				t.Fatalf("unexpected source ids: want %v, but got %v", wantIDs, gotIDs)
+			}
+			wantTSV, gotTSV := &bytes.Buffer{}, &bytes.Buffer{}
+			if err := want.WriteTSV(wantTSV); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := chain.WriteTSV(gotTSV); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if wantTSV.String() != gotTSV.String() {
+				t.Fatalf("unexpected chain: want\n%s\nbut got\n%s", wantTSV, gotTSV)
+			}
+		})
+	}
+}
+
+func TestChain_Forget_WithUnknownPost_ReturnsFalse(t *testing.T) {
+	// arrange
+	chain := markov.NewChain(1)
+	chain.AddAttributedSource("1", []string{"a"}, 1)
+
+	// act
+	ok := chain.Forget("2")
+
+	// assert
+	if ok {
+		t.Fatalf("unknown post is forgotten")
+	}
+	if got := chain.Generate(); !reflect.DeepEqual([]string{"a"}, got) {
+		t.Fatalf("unexpected sequence: %q", got)
+	}
+}
+
+func TestChain_Forget_PersistsAcrossDumpAndLoad(t *testing.T) {
+	// arrange
+	chain := markov.NewChain(1)
+	chain.AddAttributedSource("1", []string{"a"}, 1)
+	chain.AddAttributedSource("2", []string{"b"}, 1)
+	dumped, err := chain.Dump()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	loaded, err := markov.LoadChain(dumped)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// act
+	ok := loaded.Forget("2")
+
+	// assert
+	if !ok {
+		t.Fatalf("post is not forgotten")
+	}
+	for i := 0; i < 10; i++ {
+		if got := loaded.Generate(); !reflect.DeepEqual([]string{"a"}, got) {
+			t.Fatalf("unexpected sequence: %q", got)
+		}
+	}
+}
+
+func TestChain_AddAttributedSource_RecordsEachTokenOnce(t *testing.T) {
+	// arrange
+	chain := markov.NewChain(1)
+	chain.AddAttributedSource("1", []string{"a", "b", "a"}, 1)
+	dumped, err := chain.Dump()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	loaded, err := markov.LoadChain(dumped)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// act
+	loaded.AddAttributedSource("2", []string{"b", "c"}, 0.5)
+
+	// assert
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(want, loaded.SourceTokens) {
+		t.Fatalf("unexpected source tokens: want %q, but got %q", want, loaded.SourceTokens)
+	}
+	want := map[string][]markov.Contribution{
+		"1": {{Tokens: []int{0, 1, 0}, Weight: 1}},
+		"2": {{Tokens: []int{1, 2}, Weight: 0.5}},
+	}
+	if !reflect.DeepEqual(want, loaded.Sources) {
+		t.Fatalf("unexpected sources: want %+v, but got %+v", want, loaded.Sources)
+	}
+}
+
+func TestChain_Forget_RemovesTokensNoLongerReferred(t *testing.T) {
+	// arrange
+	chain := markov.NewChain(1)
+	chain.AddAttributedSource("1", []string{"a", "b"}, 1)
+	chain.AddAttributedSource("2", []string{"c", "b", "d"}, 1)
+	chain.AddAttributedSource("3", []string{"d", "a"}, 1)
+
+	// act
+	chain.Forget("2")
+	chain.AddAttributedSource("4", []string{"e", "a"}, 1)
+
+	// assert
+	if want := []string{"a", "b", "d", "e"}; !reflect.DeepEqual(want, chain.SourceTokens) {
+		t.Fatalf("unexpected source tokens: want %q, but got %q", want, chain.SourceTokens)
+	}
+	want := map[string][]markov.Contribution{
+		"1": {{Tokens: []int{0, 1}, Weight: 1}},
+		"3": {{Tokens: []int{2, 0}, Weight: 1}},
+		"4": {{Tokens: []int{3, 0}, Weight: 1}},
+	}
+	if !reflect.DeepEqual(want, chain.Sources) {
+		t.Fatalf("unexpected sources: want %+v, but got %+v", want, chain.Sources)
+	}
+}
+
+func TestChain_Forget_AfterPrune_NeverLeavesDeadEnds(t *testing.T) {
+	// arrange
+	chain := markov.NewChain(1)
+	// the transition from "s" to "b" of post 2 is pruned, while post 2 still counts on "p" to "s"
+	chain.AddAttributedSource("1", []string{"p", "s", "a"}, 2)
+	chain.AddAttributedSource("2", []string{"p", "s", "b"}, 1)
+	chain.AddAttributedSource("3", []string{"q"}, 2)
+	chain.Prune(markov.PruneOptions{MinOccurrences: 1.5})
+
+	// act
+	ok := chain.Forget("1")
+
+	// assert
+	if !ok {
+		t.Fatalf("post is not forgotten")
+	}
+	assertContextsReachEOS(t, chain)
+	for i := 0; i < 10; i++ {
+		if got := chain.Generate(); !reflect.DeepEqual([]string{"q"}, got) {
+			t.Fatalf("unexpected sequence: %q", got)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package markov
+
+import (
+	"sort"
+)
+
+// Options to reduce the size of a chain. Zero values disable each limit.
+type PruneOptions struct {
+	// Drops transitions seen fewer times than this, or with less total weight for weighted sources.
+	MinOccurrences float64 `json:"min_occurrences,omitempty"`
+	// Keeps only this number of the most frequent transitions from each state.
+	MaxChildren int `json:"max_children,omitempty"`
+	// Keeps only this number of the most frequent states, counted by the occurrences of their transitions.
+	MaxStates int `json:"max_states,omitempty"`
+}
+
+func (o PruneOptions) enabled() bool {
+	return 0 < o.MinOccurrences || 0 < o.MaxChildren || 0 < o.MaxStates
+}
+
+// PruneStats describes the size of a chain before and after pruning.
+type PruneStats struct {
+	Options           PruneOptions `json:"options"`
+	StatesBefore      int          `json:"states_before"`
+	StatesAfter       int          `json:"states_after"`
+	TransitionsBefore int          `json:"transitions_before"`
+	TransitionsAfter  int          `json:"transitions_after"`
+}
+
+type prunedState struct {
+	path []string
+	node *chainNode
+}
+
+// Removes infrequent transitions and states to reduce the size of this chain.
+//
+// On chains without variable order, states unreachable from the initial state
+// and states without a path to EOS are also removed after applying the limits,
+// so that generation never gets stuck in a state without transitions.
+// On variable-order chains, the limits are applied to contexts of every order,
+// and the same applies to contexts of a single token, which generation with backoff falls back to.
+// Longer contexts can be left without continuations, since their nodes also count the transitions
+// of shorter contexts; generation backs off from them.
+func (c *Chain) Prune(opts PruneOptions) PruneStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := PruneStats{Options: opts}
+	stats.StatesBefore, stats.TransitionsBefore = c.countStates()
+	if opts.enabled() {
+		if c.VariableOrder {
+			c.pruneVariableOrder(opts)
+		} else {
+			c.pruneFixedOrder(opts)
+		}
+		removeEmptyNodes(c.RootNode)
+	}
+	stats.StatesAfter, stats.TransitionsAfter = c.countStates()
+	return stats
+}
+
+func (c *Chain) countStates() (int, int) {
+	states, transitions := 0, 0
+	walkStates(c.RootNode, c.StateSize, func(node *chainNode) {
+		if len(node.Children) == 0 {
+			return
+		}
+		states++
+		transitions += len(node.Children)
+	})
+	return states, transitions
+}
+
+func (c *Chain) pruneFixedOrder(opts PruneOptions) {
+	states := collectStates(c.RootNode, []string{}, c.StateSize)
+	for _, s := range states {
+		pruneChildren(s.node, opts)
+	}
+	evictStates(states, opts.MaxStates, c.findTailNode(bosState(c.StateSize)))
+	c.removeDeadEnds()
+}
+
+func (c *Chain) pruneVariableOrder(opts PruneOptions) {
+	// children of the root node are unigrams, which are not transitions
+	for depth := 1; depth <= c.StateSize; depth++ {
+		for _, s := range collectStates(c.RootNode, []string{}, depth) {
+			pruneChildren(s.node, opts)
+		}
+	}
+	evictStates(collectStates(c.RootNode, []string{}, c.StateSize), opts.MaxStates, c.findTailNode(bosState(c.StateSize)))
+	c.removeDeadEnds()
+}
+
+// Removes states which generation cannot reach or leave toward EOS after transitions are removed.
+// On variable-order chains, contexts of a single token are checked,
+// and their dead tokens are removed from contexts of every order.
+func (c *Chain) removeDeadEnds() {
+	if !c.VariableOrder {
+		c.removeDeadStates(collectStates(c.RootNode, []string{}, c.StateSize), c.findTailNode(bosState(c.StateSize)))
+		return
+	}
+
+	// remove tokens whose single token context leads to dead ends from contexts of every order
+	dead := c.removeDeadStates(collectStates(c.RootNode, []string{}, 1), c.findTailNode(bosState(1)))
+	deadTokens := make(map[string]bool, len(dead))
+	for _, s := range dead {
+		// EOS is counted as a unigram but never followed by any token
+		if s.path[0] != EOS {
+			deadTokens[s.path[0]] = true
+		}
+	}
+	removeTokens(c.RootNode, deadTokens)
+}
+
+// Drops children seen fewer than MinOccurrences times and keeps MaxChildren most frequent ones.
+// Children without occurrences are not transitions but prefixes of longer contexts,
+// e.g. BOS following BOS in variable-order chains, so they are kept.
+func pruneChildren(node *chainNode, opts PruneOptions) {
+	keys := make([]string, 0, len(node.Children))
+	for k, v := range node.Children {
+		if v.Occurrences == 0 {
+			continue
+		}
+		if v.Occurrences < opts.MinOccurrences {
+			delete(node.Children, k)
+			continue
+		}
+		keys = append(keys, k)
+	}
+	if opts.MaxChildren <= 0 || len(keys) <= opts.MaxChildren {
+		return
+	}
+	sort.Strings(keys)
+	sort.SliceStable(keys, func(i, j int) bool {
+		return node.Children[keys[i]].Occurrences > node.Children[keys[j]].Occurrences
+	})
+	for _, k := range keys[opts.MaxChildren:] {
+		delete(node.Children, k)
+	}
+}
+
+// Removes transitions of the least frequent states so that at most maxStates states remain.
+// The initial state is never removed.
+func evictStates(states []prunedState, maxStates int, initial *chainNode) {
+	if maxStates <= 0 {
+		return
+	}
+	frequencies := make(map[*chainNode]float64, len(states))
+	candidates := make([]prunedState, 0, len(states))
+	remaining := 0
+	for _, s := range states {
+		if len(s.node.Children) == 0 {
+			continue
+		}
+		remaining++
+		if s.node == initial {
+			continue
+		}
+		for _, child := range s.node.Children {
+			frequencies[s.node] += child.Occurrences
+		}
+		candidates = append(candidates, s)
+	}
+	// states are listed in order of paths, so ties are broken deterministically
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return frequencies[candidates[i].node] < frequencies[candidates[j].node]
+	})
+	for i := 0; i < len(candidates) && maxStates < remaining; i++ {
+		candidates[i].node.Children = map[string]*chainNode{}
+		remaining--
+	}
+}
+
+// Removes states unreachable from the initial state or without a path to EOS,
+// and transitions into such states. Returns the removed states.
+func (c *Chain) removeDeadStates(states []prunedState, initial *chainNode) []prunedState {
+	// returns the state after the transition, or nil for EOS or missing states
+	nextState := func(s prunedState, token string) *chainNode {
+		if token == EOS {
+			return nil
+		}
+		return c.findTailNode(append(append([]string{}, s.path[1:]...), token))
+	}
+
+	// find states reachable from the initial state
+	reachable := map[*chainNode]bool{}
+	byNode := make(map[*chainNode]prunedState, len(states))
+	for _, s := range states {
+		byNode[s.node] = s
+	}
+	if initial != nil {
+		reachable[initial] = true
+		queue := []*chainNode{initial}
+		for len(queue) > 0 {
+			s := byNode[queue[0]]
+			queue = queue[1:]
+			for token := range s.node.Children {
+				next := nextState(s, token)
+				if next != nil && !reachable[next] {
+					reachable[next] = true
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+
+	// find states which have a path to EOS, by walking transitions backward
+	previous := map[*chainNode][]*chainNode{}
+	live := map[*chainNode]bool{}
+	queue := []*chainNode{}
+	for _, s := range states {
+		for token := range s.node.Children {
+			if token == EOS {
+				if !live[s.node] {
+					live[s.node] = true
+					queue = append(queue, s.node)
+				}
+				continue
+			}
+			if next := nextState(s, token); next != nil {
+				previous[next] = append(previous[next], s.node)
+			}
+		}
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, prev := range previous[node] {
+			if !live[prev] {
+				live[prev] = true
+				queue = append(queue, prev)
+			}
+		}
+	}
+
+	// every state on a path between kept states is also kept, so a single pass suffices
+	dead := []prunedState{}
+	for _, s := range states {
+		if !reachable[s.node] || !live[s.node] {
+			dead = append(dead, s)
+			s.node.Children = map[string]*chainNode{}
+			continue
+		}
+		for token := range s.node.Children {
+			if token == EOS {
+				continue
+			}
+			if next := nextState(s, token); next == nil || !reachable[next] || !live[next] {
+				delete(s.node.Children, token)
+			}
+		}
+	}
+	return dead
+}
+
+// Returns nodes at the depth with their paths in order of paths.
+func collectStates(node *chainNode, path []string, depth int) []prunedState {
+	if depth == 0 {
+		return []prunedState{{path: path, node: node}}
+	}
+	res := []prunedState{}
+	keys, _ := node.listChildren()
+	for _, k := range keys {
+		res = append(res, collectStates(node.Children[k], append(path[:len(path):len(path)], k), depth-1)...)
+	}
+	return res
+}
+
+func removeTokens(node *chainNode, tokens map[string]bool) {
+	for k, child := range node.Children {
+		if tokens[k] {
+			delete(node.Children, k)
+			continue
+		}
+		removeTokens(child, tokens)
+	}
+}
+
+// Removes descendants which neither have occurrences nor lead to nodes with occurrences.
+func removeEmptyNodes(node *chainNode) {
+	for k, child := range node.Children {
+		removeEmptyNodes(child)
+		if len(child.Children) == 0 && child.Occurrences == 0 {
+			delete(node.Children, k)
+		}
+	}
+}
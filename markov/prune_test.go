@@ -0,0 +1,165 @@
+package markov_test
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/paralleltree/markov-bot-go/markov"
+)
+
+func TestChain_Prune_WithMinOccurrences_RemovesRareTransitionsAndDeadStates(t *testing.T) {
+	// arrange
+	chain := markov.NewChain(1)
+	chain.AddSource([]string{"a", "b"})
+	chain.AddSource([]string{"a", "b"})
+	chain.AddSource([]string{"a", "c", "d"})
+	want := markov.PruneStats{
+		Options:           markov.PruneOptions{MinOccurrences: 2},
+		StatesBefore:      5,
+		StatesAfter:       3,
+		TransitionsBefore: 6,
+		TransitionsAfter:  3,
+	}
+
+	// act
+	got := chain.Prune(markov.PruneOptions{MinOccurrences: 2})
+
+	// assert
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("unexpected stats: want %+v, but got %+v", want, got)
+	}
+	for i := 0; i < 10; i++ {
+		if generated := chain.Generate(); !reflect.DeepEqual([]string{"a", "b"}, generated) {
+			t.Fatalf("unexpected sequence: %q", generated)
+		}
+	}
+}
+
+func TestChain_Prune_NeverLeavesDeadEnds(t *testing.T) {
+	cases := []struct {
+		name          string
+		stateSize     int
+		variableOrder bool
+		opts          markov.PruneOptions
+	}{
+		{name: "min occurrences", stateSize: 1, opts: markov.PruneOptions{MinOccurrences: 2}},
+		{name: "max children", stateSize: 1, opts: markov.PruneOptions{MaxChildren: 1}},
+		{name: "max states", stateSize: 1, opts: markov.PruneOptions{MaxStates: 100}},
+		{name: "all limits", stateSize: 1, opts: markov.PruneOptions{MinOccurrences: 2, MaxChildren: 3, MaxStates: 100}},
+		{name: "variable order", stateSize: 1, variableOrder: true, opts: markov.PruneOptions{MinOccurrences: 2, MaxChildren: 3, MaxStates: 100}},
+		{name: "variable order with state size 2", stateSize: 2, variableOrder: true, opts: markov.PruneOptions{MinOccurrences: 2, MaxChildren: 3, MaxStates: 100}},
+		{name: "variable order with state size 3", stateSize: 3, variableOrder: true, opts: markov.PruneOptions{MinOccurrences: 2, MaxChildren: 3, MaxStates: 100}},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			chainOpts := []func(*markov.Chain){}
+			if tt.variableOrder {
+				chainOpts = append(chainOpts, markov.WithVariableOrder())
+			}
+			chain := buildRandomChain(tt.stateSize, 500, chainOpts...)
+			r := rand.New(rand.NewSource(1))
+
+			// act
+			stats := chain.Prune(tt.opts)
+
+			// assert
+			if stats.StatesBefore <= stats.StatesAfter || stats.StatesAfter == 0 {
+				t.Fatalf("unexpected number of states: %+v", stats)
+			}
+			if 0 < tt.opts.MaxStates && tt.opts.MaxStates < stats.StatesAfter {
+				t.Fatalf("states should be at most %d, but got %d", tt.opts.MaxStates, stats.StatesAfter)
+			}
+			// generation returns nothing when it reaches a state without transitions
+			// variable-order chains back off to shorter contexts instead
+			// contexts without any continuation are not backed off from without thresholds
+			for _, backoff := range []markov.BackoffOptions{markov.DefaultBackoffOptions(), {}} {
+				for i := 0; i < 200; i++ {
+					if generated := chain.GenerateWithOptions(markov.WithRand(r), markov.WithBackoff(backoff)); len(generated) == 0 {
+						t.Fatalf("generation with %+v should reach EOS", backoff)
+					}
+				}
+			}
+			if tt.variableOrder {
+				assertContextsReachEOS(t, chain)
+			}
+		})
+	}
+}
+
+// Asserts that every token following a remaining context of a variable-order chain leads to EOS.
+// Generation can always back off to the context of the last token,
+// so every token must be EOS or have a single token context with a path to EOS.
+func assertContextsReachEOS(t *testing.T, chain *markov.Chain) {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	if err := chain.WriteTSV(buf); err != nil {
+		t.Fatalf("unexpected error while exporting chain: %v", err)
+	}
+
+	// quoted contexts to the tokens following them
+	next := map[string][]string{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		fields := strings.Split(line, "\t")
+		if strings.HasPrefix(line, "#") || fields[0] == "" {
+			continue
+		}
+		token, err := strconv.Unquote(fields[1])
+		if err != nil {
+			t.Fatalf("unexpected token %s: %v", fields[1], err)
+		}
+		next[fields[0]] = append(next[fields[0]], token)
+	}
+
+	// single token contexts with a path to EOS, found by repeating until no context is added
+	live := map[string]bool{}
+	for changed := true; changed; {
+		changed = false
+		for context, tokens := range next {
+			if live[context] || strings.Contains(context, " ") {
+				continue
+			}
+			for _, token := range tokens {
+				if token == markov.EOS || live[strconv.Quote(token)] {
+					live[context] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	for context, tokens := range next {
+		for _, token := range tokens {
+			if token != markov.EOS && !live[strconv.Quote(token)] {
+				t.Fatalf("token %q following context %s does not lead to EOS", token, context)
+			}
+		}
+	}
+}
+
+func TestChain_Prune_WithoutOptions_KeepsChain(t *testing.T) {
+	// arrange
+	chain := buildRandomChain(2, 50)
+	want, err := chain.Dump()
+	if err != nil {
+		t.Fatalf("unexpected error while dumping chain: %v", err)
+	}
+
+	// act
+	chain.Prune(markov.PruneOptions{})
+
+	// assert
+	got, err := chain.Dump()
+	if err != nil {
+		t.Fatalf("unexpected error while dumping chain: %v", err)
+	}
+	if string(want) != string(got) {
+		t.Fatalf("chain should not be changed")
+	}
+}
@@ -0,0 +1,119 @@
+package markov
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Options to choose the next token from candidates weighted by their occurrences.
+// They are applied in order of temperature, top-k and top-p.
+type SamplingOptions struct {
+	// Always chooses the most frequent token.
+	Greedy bool
+	// Scales the weights by occurrences^(1/Temperature).
+	// Lower values make frequent tokens more likely, and higher values flatten the distribution.
+	// Zero or less is regarded as 1.
+	Temperature float64
+	// Chooses only from the K most frequent tokens. Zero or less disables it.
+	TopK int
+	// Chooses only from the most frequent tokens whose cumulative probability reaches TopP.
+	// Zero or less, or 1 or more disables it.
+	TopP float64
+}
+
+func DefaultSamplingOptions() SamplingOptions {
+	return SamplingOptions{
+		Temperature: 1,
+	}
+}
+
+// Returns true if the options sample proportionally to occurrences.
+func (o SamplingOptions) isProportional() bool {
+	return !o.Greedy && (o.Temperature <= 0 || o.Temperature == 1) && o.TopK <= 0 && (o.TopP <= 0 || 1 <= o.TopP)
+}
+
+// Returns the index of chosen candidate.
+func (o SamplingOptions) sample(r *rand.Rand, t *transitions) int {
+	occurrences := t.occurrences
+	if o.Greedy {
+		return argmax(occurrences)
+	}
+	if o.isProportional() {
+		threshold := float64In(r, t.total())
+		return sort.Search(len(t.cumsum)-1, func(i int) bool { return threshold < t.cumsum[i] })
+	}
+
+	// sort candidates in descending order of occurrences
+	indices := make([]int, len(occurrences))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		return occurrences[indices[i]] > occurrences[indices[j]]
+	})
+	// candidates without occurrences, e.g. reduced by forgetting posts, are never chosen,
+	// and would make the weights NaN if the most frequent one has no occurrences
+	for len(indices) > 0 && occurrences[indices[len(indices)-1]] <= 0 {
+		indices = indices[:len(indices)-1]
+	}
+	if len(indices) == 0 {
+		return argmax(occurrences)
+	}
+	if 0 < o.TopK && o.TopK < len(indices) {
+		indices = indices[:o.TopK]
+	}
+
+	temperature := o.Temperature
+	if temperature <= 0 {
+		temperature = 1
+	}
+	// scale in log space to avoid overflow with low temperatures
+	maxLog := math.Log(occurrences[indices[0]]) / temperature
+	weights := make([]float64, len(indices))
+	sum := 0.0
+	for i, idx := range indices {
+		weights[i] = math.Exp(math.Log(occurrences[idx])/temperature - maxLog)
+		sum += weights[i]
+	}
+
+	if 0 < o.TopP && o.TopP < 1 {
+		accum := 0.0
+		for i, w := range weights {
+			accum += w
+			if o.TopP*sum <= accum {
+				weights = weights[:i+1]
+				sum = accum
+				break
+			}
+		}
+	}
+
+	threshold := float64In(r, sum)
+	accum := 0.0
+	for i, w := range weights {
+		accum += w
+		if threshold < accum {
+			return indices[i]
+		}
+	}
+	return indices[len(weights)-1]
+}
+
+func argmax(values []float64) int {
+	res := 0
+	for i, v := range values {
+		if values[res] < v {
+			res = i
+		}
+	}
+	return res
+}
+
+// Returns a random float in [0, n).
+func float64In(r *rand.Rand, n float64) float64 {
+	if r == nil {
+		return rand.Float64() * n
+	}
+	return r.Float64() * n
+}
@@ -0,0 +1,167 @@
+package markov_test
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/paralleltree/markov-bot-go/markov"
+)
+
+// Builds a chain where "A" is followed by "B" three times and by "C" once.
+func buildSamplingTestChain() *markov.Chain {
+	chain := markov.NewChain(1)
+	for i := 0; i < 3; i++ {
+		chain.AddSource([]string{"A", "B"})
+	}
+	chain.AddSource([]string{"A", "C"})
+	return chain
+}
+
+func TestChain_GenerateWithOptions_SamplesWithStrategy(t *testing.T) {
+	cases := []struct {
+		name     string
+		sampling markov.SamplingOptions
+		wantB    bool
+		wantC    bool
+	}{
+		{
+			name:     "proportional",
+			sampling: markov.DefaultSamplingOptions(),
+			wantB:    true,
+			wantC:    true,
+		},
+		{
+			name:     "greedy",
+			sampling: markov.SamplingOptions{Greedy: true},
+			wantB:    true,
+		},
+		{
+			name:     "top-k",
+			sampling: markov.SamplingOptions{TopK: 1},
+			wantB:    true,
+		},
+		{
+			name:     "top-p",
+			sampling: markov.SamplingOptions{TopP: 0.5},
+			wantB:    true,
+		},
+		{
+			name:     "low temperature",
+			sampling: markov.SamplingOptions{Temperature: 0.01},
+			wantB:    true,
+		},
+		{
+			name:     "high temperature",
+			sampling: markov.SamplingOptions{Temperature: 100},
+			wantB:    true,
+			wantC:    true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			chain := buildSamplingTestChain()
+			r := rand.New(rand.NewSource(1))
+
+			// act
+			gotB, gotC := false, false
+			for i := 0; i < 200; i++ {
+				result := chain.GenerateWithOptions(markov.WithSampling(tt.sampling), markov.WithRand(r))
+				switch {
+				case slices.Equal([]string{"A", "B"}, result):
+					gotB = true
+				case slices.Equal([]string{"A", "C"}, result):
+					gotC = true
+				default:
+					t.Fatalf("unexpected result: %v", result)
+				}
+			}
+
+			// assert
+			if tt.wantB != gotB {
+				t.Errorf("unexpected occurrence of \"A B\": want %v, but got %v", tt.wantB, gotB)
+			}
+			if tt.wantC != gotC {
+				t.Errorf("unexpected occurrence of \"A C\": want %v, but got %v", tt.wantC, gotC)
+			}
+		})
+	}
+}
+
+func TestChain_GenerateWithOptions_NeverChoosesCandidateWithoutOccurrences(t *testing.T) {
+	// arrange
+	// "A" is followed by "B" no times, e.g. after forgetting posts, and by "C" once
+	data := `{"state_size":1,"root_node":{"occurences":0,"children":{` +
+		`"__BOS__":{"occurences":0,"children":{"A":{"occurences":1,"children":{}}}},` +
+		`"A":{"occurences":0,"children":{"B":{"occurences":0,"children":{}},"C":{"occurences":1,"children":{}}}},` +
+		`"B":{"occurences":0,"children":{"__EOS__":{"occurences":0,"children":{}}}},` +
+		`"C":{"occurences":0,"children":{"__EOS__":{"occurences":1,"children":{}}}}}}}`
+	chain, err := markov.LoadChain([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r := rand.New(rand.NewSource(1))
+	sampling := markov.SamplingOptions{Temperature: 0.5, TopK: 2}
+
+	// act & assert
+	for i := 0; i < 100; i++ {
+		if got := chain.GenerateWithOptions(markov.WithSampling(sampling), markov.WithRand(r)); !slices.Equal([]string{"A", "C"}, got) {
+			t.Fatalf("unexpected result: %v", got)
+		}
+	}
+}
+
+func TestChain_Generate_ChoosesEveryCandidate(t *testing.T) {
+	// arrange
+	chain := markov.NewChain(1)
+	chain.AddSource([]string{"A"})
+	chain.AddSource([]string{"B"})
+
+	// act
+	results := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		results[chain.Join(chain.Generate())] = true
+	}
+
+	// assert
+	if !results["A"] || !results["B"] {
+		t.Fatalf("every candidate should be chosen, but got %v", results)
+	}
+}
+
+func TestChain_GenerateWithOptions_WithSameSeed_ReturnsSameSequence(t *testing.T) {
+	// arrange
+	chain := markov.NewChain(1)
+	for _, source := range [][]string{
+		{"A", "B", "C", "D"},
+		{"A", "C", "B", "D"},
+		{"B", "A", "D", "C"},
+		{"D", "C", "A", "B"},
+	} {
+		chain.AddSource(source)
+	}
+	dump, err := chain.Dump()
+	if err != nil {
+		t.Fatalf("unexpected error while dumping chain: %v", err)
+	}
+
+	// act
+	results := [][]string{}
+	for i := 0; i < 10; i++ {
+		// restored chains have maps with different iteration orders
+		restored, err := markov.LoadChain(dump)
+		if err != nil {
+			t.Fatalf("unexpected error while loading chain: %v", err)
+		}
+		results = append(results, restored.GenerateWithOptions(markov.WithRand(rand.New(rand.NewSource(42)))))
+	}
+
+	// assert
+	for _, result := range results[1:] {
+		if !slices.Equal(results[0], result) {
+			t.Fatalf("results should be same with the same seed: %v", results)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package markov
+
+import (
+	"math"
+	"sort"
+)
+
+// Returns the surprisal, the negative natural logarithm of the probability, of each token and the following EOS.
+// The result has len(tokens)+1 elements.
+// For variable-order chains, each token is predicted from the longest context followed by the token.
+// A token never seen after the context has the surprisal of +Inf.
+func (c *Chain) Surprisals(tokens []string) []float64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return surprisals(c, c.StateSize, c.VariableOrder, tokens)
+}
+
+// Returns the natural logarithm of the probability that this chain generates the tokens.
+func (c *Chain) LogProbability(tokens []string) float64 {
+	return logProbability(c.Surprisals(tokens))
+}
+
+func (c *FrozenChain) Surprisals(tokens []string) []float64 {
+	return surprisals(c, c.StateSize, c.VariableOrder, tokens)
+}
+
+func (c *FrozenChain) LogProbability(tokens []string) float64 {
+	return logProbability(c.Surprisals(tokens))
+}
+
+// Returns the perplexity from surprisals, which is the exponential of their mean.
+// Lower perplexity means that the sequence is more predictable, or more likely copied from the source.
+func Perplexity(surprisals []float64) float64 {
+	if len(surprisals) == 0 {
+		return 0
+	}
+	return math.Exp(-logProbability(surprisals) / float64(len(surprisals)))
+}
+
+func logProbability(surprisals []float64) float64 {
+	sum := 0.0
+	for _, v := range surprisals {
+		sum += v
+	}
+	return -sum
+}
+
+func surprisals(src transitionSource, stateSize int, variableOrder bool, tokens []string) []float64 {
+	run := makeRun(stateSize, tokens)
+	res := make([]float64, 0, len(run)-stateSize)
+	for i := stateSize; i < len(run); i++ {
+		state := run[i-stateSize : i]
+		res = append(res, surprisal(src, state, variableOrder, run[i]))
+	}
+	return res
+}
+
+func surprisal(src transitionSource, state []string, variableOrder bool, token string) float64 {
+	minOrder := len(state)
+	if variableOrder {
+		minOrder = 1
+	}
+	for order := len(state); order >= minOrder; order-- {
+		t := src.lookup(state[len(state)-order:])
+		if t == nil {
+			continue
+		}
+		if occurrences := t.occurrencesOf(token); occurrences > 0 {
+			return math.Log(t.total() / occurrences)
+		}
+	}
+	return math.Inf(1)
+}
+
+// Returns the occurrences of the token in the candidates.
+func (t *transitions) occurrencesOf(token string) float64 {
+	i := sort.SearchStrings(t.items, token)
+	if i < len(t.items) && t.items[i] == token {
+		return t.occurrences[i]
+	}
+	return 0
+}
@@ -0,0 +1,96 @@
+package markov_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/paralleltree/markov-bot-go/markov"
+)
+
+func TestChain_LogProbability(t *testing.T) {
+	// arrange
+	chain := markov.NewChain(1)
+	for i := 0; i < 3; i++ {
+		chain.AddSource([]string{"A", "B"})
+	}
+	chain.AddSource([]string{"A", "C"})
+
+	cases := []struct {
+		name   string
+		tokens []string
+		want   float64
+	}{
+		{
+			name:   "frequent sequence",
+			tokens: []string{"A", "B"},
+			want:   math.Log(0.75),
+		},
+		{
+			name:   "rare sequence",
+			tokens: []string{"A", "C"},
+			want:   math.Log(0.25),
+		},
+		{
+			name:   "unseen sequence",
+			tokens: []string{"B", "A"},
+			want:   math.Inf(-1),
+		},
+		{
+			name:   "sequence not ending at EOS",
+			tokens: []string{"A"},
+			want:   math.Inf(-1),
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			got := chain.LogProbability(tt.tokens)
+			gotFrozen := chain.Freeze().LogProbability(tt.tokens)
+
+			// assert
+			if math.Abs(tt.want-got) > 1e-9 && !(math.IsInf(tt.want, -1) && math.IsInf(got, -1)) {
+				t.Fatalf("unexpected log probability: want %v, but got %v", tt.want, got)
+			}
+			if got != gotFrozen && !(math.IsInf(got, -1) && math.IsInf(gotFrozen, -1)) {
+				t.Fatalf("frozen chain should return the same value: want %v, but got %v", got, gotFrozen)
+			}
+		})
+	}
+}
+
+func TestChain_Surprisals_WithVariableOrder_BacksOffToContextFollowedByToken(t *testing.T) {
+	// arrange
+	chain := markov.NewChain(2, markov.WithVariableOrder())
+	chain.AddSource([]string{"A", "B", "C"})
+	chain.AddSource([]string{"X", "B", "D"})
+
+	// act
+	got := chain.Surprisals([]string{"A", "B", "D"})
+
+	// assert
+	// "D" never follows "A B", but follows "B" with probability 1/2
+	want := []float64{math.Log(2), 0, math.Log(2), 0}
+	if len(want) != len(got) {
+		t.Fatalf("unexpected length: want %d, but got %d", len(want), len(got))
+	}
+	for i := range want {
+		if math.Abs(want[i]-got[i]) > 1e-9 {
+			t.Fatalf("unexpected surprisals: want %v, but got %v", want, got)
+		}
+	}
+}
+
+func TestPerplexity(t *testing.T) {
+	// arrange
+	surprisals := []float64{math.Log(2), math.Log(8)}
+
+	// act
+	got := markov.Perplexity(surprisals)
+
+	// assert
+	want := 4.0
+	if math.Abs(want-got) > 1e-9 {
+		t.Fatalf("unexpected perplexity: want %v, but got %v", want, got)
+	}
+}
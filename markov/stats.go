@@ -0,0 +1,147 @@
+package markov
+
+import (
+	"sort"
+)
+
+// Stats describes the contents of a chain.
+// States are contexts of StateSize tokens followed by at least one token.
+type Stats struct {
+	Version       int       `json:"version"`
+	Unit          TokenUnit `json:"unit"`
+	StateSize     int       `json:"state_size"`
+	VariableOrder bool      `json:"variable_order"`
+
+	// The number of distinct tokens except BOS and EOS.
+	VocabularySize   int `json:"vocabulary_size"`
+	StatesCount      int `json:"states_count"`
+	TransitionsCount int `json:"transitions_count"`
+	// The proportion of states followed by only one token, which tend to copy the source verbatim.
+	DeterministicStatesRatio float64                 `json:"deterministic_states_ratio"`
+	BranchingFactors         []BranchingFactorBucket `json:"branching_factors"`
+	TopTokens                []TokenCount            `json:"top_tokens"`
+	TopSentenceStarters      []TokenCount            `json:"top_sentence_starters"`
+}
+
+// BranchingFactorBucket is the number of states followed by Min to Max distinct tokens.
+type BranchingFactorBucket struct {
+	Min         int `json:"min"`
+	Max         int `json:"max"`
+	StatesCount int `json:"states_count"`
+}
+
+type TokenCount struct {
+	Token       string  `json:"token"`
+	Occurrences float64 `json:"occurrences"`
+}
+
+// Returns statistics of this chain with the topN most frequent tokens and sentence starters.
+func (c *Chain) Stats(topN int) Stats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	stats := Stats{
+		Version:       c.Version,
+		Unit:          c.Unit,
+		StateSize:     c.StateSize,
+		VariableOrder: c.VariableOrder,
+	}
+
+	tokenCounts := map[string]float64{}
+	branchingFactors := map[int]int{}
+	deterministicStates := 0
+	walkStates(c.RootNode, c.StateSize, func(node *chainNode) {
+		if len(node.Children) == 0 {
+			return
+		}
+		stats.StatesCount++
+		stats.TransitionsCount += len(node.Children)
+		if len(node.Children) == 1 {
+			deterministicStates++
+		}
+		branchingFactors[bucketIndex(len(node.Children))]++
+		for k, v := range node.Children {
+			if k != EOS {
+				tokenCounts[k] += v.Occurrences
+			}
+		}
+	})
+
+	stats.VocabularySize = len(tokenCounts)
+	if stats.StatesCount > 0 {
+		stats.DeterministicStatesRatio = float64(deterministicStates) / float64(stats.StatesCount)
+	}
+	stats.BranchingFactors = []BranchingFactorBucket{}
+	for i := 0; i <= maxKey(branchingFactors); i++ {
+		min, max := bucketRange(i)
+		stats.BranchingFactors = append(stats.BranchingFactors, BranchingFactorBucket{Min: min, Max: max, StatesCount: branchingFactors[i]})
+	}
+	stats.TopTokens = topTokenCounts(tokenCounts, topN)
+
+	starterCounts := map[string]float64{}
+	if starters := c.findTailNode(bosState(c.StateSize)); starters != nil {
+		for k, v := range starters.Children {
+			if k != EOS {
+				starterCounts[k] = v.Occurrences
+			}
+		}
+	}
+	stats.TopSentenceStarters = topTokenCounts(starterCounts, topN)
+
+	return stats
+}
+
+// Calls f with every node at the depth.
+func walkStates(node *chainNode, depth int, f func(*chainNode)) {
+	if depth == 0 {
+		f(node)
+		return
+	}
+	for _, child := range node.Children {
+		walkStates(child, depth-1, f)
+	}
+}
+
+// Buckets are 1, 2, 3-4, 5-8, 9-16 and so on.
+func bucketIndex(n int) int {
+	i := 0
+	for (1 << i) < n {
+		i++
+	}
+	return i
+}
+
+func bucketRange(i int) (int, int) {
+	if i == 0 {
+		return 1, 1
+	}
+	return 1<<(i-1) + 1, 1 << i
+}
+
+func maxKey(m map[int]int) int {
+	res := -1
+	for k := range m {
+		if res < k {
+			res = k
+		}
+	}
+	return res
+}
+
+// Returns the n most frequent tokens. Ties are ordered by token.
+func topTokenCounts(counts map[string]float64, n int) []TokenCount {
+	res := make([]TokenCount, 0, len(counts))
+	for k, v := range counts {
+		res = append(res, TokenCount{Token: k, Occurrences: v})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Occurrences != res[j].Occurrences {
+			return res[i].Occurrences > res[j].Occurrences
+		}
+		return res[i].Token < res[j].Token
+	})
+	if n < len(res) {
+		res = res[:n]
+	}
+	return res
+}
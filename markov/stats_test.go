@@ -0,0 +1,57 @@
+package markov_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/paralleltree/markov-bot-go/markov"
+)
+
+func TestChain_Stats(t *testing.T) {
+	// arrange
+	chain := markov.NewChain(1)
+	chain.AddSource([]string{"A", "B"})
+	chain.AddSource([]string{"A", "B"})
+	chain.AddSource([]string{"A", "C"})
+	want := markov.Stats{
+		Version:                  markov.CurrentFormatVersion,
+		Unit:                     markov.UnitWord,
+		StateSize:                1,
+		VocabularySize:           3,
+		StatesCount:              4,
+		TransitionsCount:         5,
+		DeterministicStatesRatio: 0.75,
+		BranchingFactors: []markov.BranchingFactorBucket{
+			{Min: 1, Max: 1, StatesCount: 3},
+			{Min: 2, Max: 2, StatesCount: 1},
+		},
+		TopTokens: []markov.TokenCount{
+			{Token: "A", Occurrences: 3},
+			{Token: "B", Occurrences: 2},
+		},
+		TopSentenceStarters: []markov.TokenCount{
+			{Token: "A", Occurrences: 3},
+		},
+	}
+
+	// act
+	got := chain.Stats(2)
+
+	// assert
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("unexpected stats: want %+v, but got %+v", want, got)
+	}
+}
+
+func TestChain_Stats_WithEmptyChain(t *testing.T) {
+	// arrange
+	chain := markov.NewChain(2)
+
+	// act
+	got := chain.Stats(10)
+
+	// assert
+	if got.StatesCount != 0 || got.VocabularySize != 0 || len(got.TopTokens) != 0 || len(got.BranchingFactors) != 0 {
+		t.Fatalf("unexpected stats for empty chain: %+v", got)
+	}
+}
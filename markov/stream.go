@@ -0,0 +1,244 @@
+package markov
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Writes the chain in the same format as Dump without building the whole dump in memory.
+func (c *Chain) DumpTo(w io.Writer) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e := &chainEncoder{w: bufio.NewWriter(w)}
+	// fields are written in the order of the struct as json.Marshal does
+	e.raw("{")
+	if c.Version != 0 {
+		e.field("version", c.Version)
+	}
+	if c.Unit != "" {
+		e.field("unit", c.Unit)
+	}
+	e.field("state_size", c.StateSize)
+	if c.VariableOrder {
+		e.field("variable_order", c.VariableOrder)
+	}
+	if c.Metadata != nil {
+		e.field("metadata", c.Metadata)
+	}
+	e.key("root_node")
+	e.node(c.RootNode)
+	if len(c.SourceTokens) > 0 {
+		e.field("source_tokens", c.SourceTokens)
+	}
+	if len(c.Sources) > 0 {
+		e.field("sources", c.Sources)
+	}
+	e.raw("}")
+	if e.err != nil {
+		return fmt.Errorf("marshal chain: %w", e.err)
+	}
+	if err := e.w.Flush(); err != nil {
+		return fmt.Errorf("marshal chain: %w", err)
+	}
+	return nil
+}
+
+// Keeps the first error so that writing can be checked once at the end.
+type chainEncoder struct {
+	w     *bufio.Writer
+	err   error
+	comma bool
+}
+
+func (e *chainEncoder) raw(s string) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = e.w.WriteString(s)
+}
+
+func (e *chainEncoder) value(v any) {
+	if e.err != nil {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		e.err = err
+		return
+	}
+	_, e.err = e.w.Write(data)
+}
+
+// Writes the key of an object, preceded by a comma unless it is the first one.
+func (e *chainEncoder) key(k string) {
+	if e.comma {
+		e.raw(",")
+	}
+	e.value(k)
+	e.raw(":")
+	e.comma = true
+}
+
+func (e *chainEncoder) field(k string, v any) {
+	e.key(k)
+	e.value(v)
+}
+
+func (e *chainEncoder) node(n *chainNode) {
+	if n == nil {
+		e.raw("null")
+		return
+	}
+	e.raw(`{"children":`)
+	if n.Children == nil {
+		e.raw("null")
+	} else {
+		e.raw("{")
+		e.comma = false
+		items, _ := n.listChildren()
+		for _, k := range items {
+			e.key(k)
+			e.node(n.Children[k])
+		}
+		e.raw("}")
+	}
+	e.raw(`,"occurences":`)
+	e.value(n.Occurrences)
+	e.raw("}")
+	e.comma = true
+}
+
+// Reads a chain dumped by Dump or DumpTo from the stream.
+// Nodes are decoded one by one, so the whole dump is not held in memory.
+func ReadChain(r io.Reader) (*Chain, error) {
+	dec := json.NewDecoder(r)
+	c := &Chain{}
+	fields := map[string]any{
+		"version":        &c.Version,
+		"unit":           &c.Unit,
+		"state_size":     &c.StateSize,
+		"variable_order": &c.VariableOrder,
+		"metadata":       &c.Metadata,
+		"source_tokens":  &c.SourceTokens,
+		"sources":        &c.Sources,
+	}
+	_, err := readObject(dec, func(key string) error {
+		if key == "root_node" {
+			node, err := readNode(dec)
+			c.RootNode = node
+			return err
+		}
+		if v, ok := fields[key]; ok {
+			return dec.Decode(v)
+		}
+		return skipValue(dec)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal chain: %w", err)
+	}
+	return c, nil
+}
+
+// Reads only the metadata from a dumped chain in the stream.
+// Reading stops at the nodes, since the metadata is dumped before them, so the rest of the stream is not read.
+// Returns nil if the chain was built without metadata.
+func ReadMetadata(r io.Reader) (*Metadata, error) {
+	dec := json.NewDecoder(r)
+	var metadata *Metadata
+	_, err := readObject(dec, func(key string) error {
+		switch key {
+		case "metadata":
+			return dec.Decode(&metadata)
+		case "root_node":
+			return errStopReading
+		default:
+			return skipValue(dec)
+		}
+	})
+	if err != nil && !errors.Is(err, errStopReading) {
+		return nil, fmt.Errorf("unmarshal chain: %w", err)
+	}
+	return metadata, nil
+}
+
+var errStopReading = errors.New("stop reading")
+
+func readNode(dec *json.Decoder) (*chainNode, error) {
+	n := &chainNode{}
+	ok, err := readObject(dec, func(key string) error {
+		switch key {
+		case "children":
+			children := map[string]*chainNode{}
+			ok, err := readObject(dec, func(token string) error {
+				child, err := readNode(dec)
+				children[token] = child
+				return err
+			})
+			if ok {
+				n.Children = children
+			}
+			return err
+		case "occurences":
+			return dec.Decode(&n.Occurrences)
+		default:
+			return skipValue(dec)
+		}
+	})
+	if err != nil || !ok {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Reads an object from the decoder, calling readValue with each key to read its value.
+// Returns false if the value is null.
+func readObject(dec *json.Decoder, readValue func(key string) error) (bool, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return false, err
+	}
+	if tok == nil {
+		return false, nil
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '{' {
+		return false, fmt.Errorf("unexpected token %v: want an object", tok)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return false, err
+		}
+		if err := readValue(tok.(string)); err != nil {
+			return false, err
+		}
+	}
+	// closing brace
+	if _, err := dec.Token(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Skips a value token by token, without holding the whole value in memory.
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
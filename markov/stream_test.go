@@ -0,0 +1,155 @@
+package markov_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/paralleltree/markov-bot-go/markov"
+)
+
+func newStreamTestChains() map[string]*markov.Chain {
+	plain := markov.NewChain(2)
+	plain.AddSource([]string{"A", "B", "C"})
+	plain.AddSource([]string{"A", "B", "Z"})
+
+	full := markov.NewChain(2, markov.WithUnit(markov.UnitCharacter), markov.WithVariableOrder())
+	full.Metadata = &markov.Metadata{BuiltAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), BotName: "bot"}
+	full.AddAttributedSource("1", []string{"<a>", "&", "\"b\" "}, 0.25)
+	full.AddAttributedSource("2", []string{"日本", "語"}, 1)
+
+	return map[string]*markov.Chain{
+		"empty":                  markov.NewChain(3),
+		"plain":                  plain,
+		"with metadata, sources": full,
+	}
+}
+
+func TestChain_DumpTo_WritesSameAsDump(t *testing.T) {
+	for name, chain := range newStreamTestChains() {
+		t.Run(name, func(t *testing.T) {
+			// arrange
+			want, err := chain.Dump()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			buf := new(bytes.Buffer)
+
+			// act
+			err = chain.DumpTo(buf)
+
+			// assert
+			if err != nil {
+				t.Fatalf("DumpTo() should not return error, but got: %v", err)
+			}
+			if string(want) != buf.String() {
+				t.Fatalf("unexpected dump:\nwant %s\n got %s", want, buf.String())
+			}
+		})
+	}
+}
+
+func TestReadChain_RestoresDumpedChain(t *testing.T) {
+	for name, chain := range newStreamTestChains() {
+		t.Run(name, func(t *testing.T) {
+			// arrange
+			buf := new(bytes.Buffer)
+			if err := chain.DumpTo(buf); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			want := new(markov.Chain)
+			if err := json.Unmarshal(buf.Bytes(), want); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// act
+			got, err := markov.ReadChain(buf)
+
+			// assert
+			if err != nil {
+				t.Fatalf("ReadChain() should not return error, but got: %v", err)
+			}
+			if !reflect.DeepEqual(want, got) {
+				t.Fatalf("unexpected chain: want %v, but got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestReadChain_WithInvalidStream_ReturnsError(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+	}{
+		{name: "truncated", data: `{"state_size":1,"root_node":{"children":{"a":`},
+		{name: "not an object", data: `[1]`},
+		{name: "invalid node", data: `{"state_size":1,"root_node":{"children":[]}}`},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			_, err := markov.ReadChain(bytes.NewReader([]byte(tt.data)))
+
+			// assert
+			if err == nil {
+				t.Fatalf("ReadChain() should return error")
+			}
+		})
+	}
+}
+
+func TestReadChain_IgnoresUnknownFields(t *testing.T) {
+	// arrange
+	data := `{"state_size":1,"unknown":{"a":[1,{"b":2}]},"root_node":{"children":null,"occurences":0,"extra":[]}}`
+
+	// act
+	got, err := markov.ReadChain(bytes.NewReader([]byte(data)))
+
+	// assert
+	if err != nil {
+		t.Fatalf("ReadChain() should not return error, but got: %v", err)
+	}
+	if got.StateSize != 1 || got.RootNode == nil || got.RootNode.Children != nil {
+		t.Fatalf("unexpected chain: %+v", got)
+	}
+}
+
+// fails reading after the given data
+type failingReader struct {
+	r io.Reader
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if err == io.EOF {
+		return n, errors.New("read after the data")
+	}
+	return n, err
+}
+
+func TestReadMetadata_DoesNotReadNodes(t *testing.T) {
+	// arrange
+	chain := newStreamTestChains()["with metadata, sources"]
+	buf := new(bytes.Buffer)
+	if err := chain.DumpTo(buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// cut the dump in the middle of the nodes
+	head := buf.Bytes()[:bytes.Index(buf.Bytes(), []byte(`"root_node"`))+len(`"root_node":{"chi`)]
+
+	// act
+	got, err := markov.ReadMetadata(&failingReader{r: bytes.NewReader(head)})
+
+	// assert
+	if err != nil {
+		t.Fatalf("ReadMetadata() should not return error, but got: %v", err)
+	}
+	if !reflect.DeepEqual(chain.Metadata, got) {
+		t.Fatalf("unexpected metadata: want %+v, but got %+v", chain.Metadata, got)
+	}
+}
@@ -3,3 +3,34 @@ package morpheme
 type MorphemeAnalyzer interface {
 	Analyze(sentence string) ([][]string, error)
 }
+
+type analyzerConf struct {
+	preprocessor *Preprocessor
+}
+
+// Replaces the preprocessing pipeline applied before analyzing.
+// If p is nil, the default pipeline is kept.
+func WithPreprocessor(p *Preprocessor) func(c *analyzerConf) {
+	return func(c *analyzerConf) {
+		if p != nil {
+			c.preprocessor = p
+		}
+	}
+}
+
+// Disables preprocessing, for analyzers receiving texts already preprocessed.
+func WithoutPreprocessing() func(c *analyzerConf) {
+	return func(c *analyzerConf) {
+		c.preprocessor = &Preprocessor{}
+	}
+}
+
+func newAnalyzerConf(optFns ...func(*analyzerConf)) *analyzerConf {
+	conf := &analyzerConf{
+		preprocessor: DefaultPreprocessor(),
+	}
+	for _, f := range optFns {
+		f(conf)
+	}
+	return conf
+}
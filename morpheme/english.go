@@ -0,0 +1,62 @@
+package morpheme
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Matches a token from the head of a text.
+// Contractions ("don't"), abbreviations ("e.g.") and numbers ("12:00", "1,000.5") are kept as single tokens,
+// and each run of punctuations forms its own token.
+var englishTokenPattern = regexp.MustCompile(`^(?:` +
+	`(?:[A-Za-z]\.){2,}` + // abbreviations
+	`|[0-9]+(?:[.,:][0-9]+)*` + // numbers
+	`|[A-Za-z0-9]+(?:['’][A-Za-z]+)*` + // words and contractions
+	`|[[:punct:]]+` + // punctuations
+	`|[^\s\p{Z}A-Za-z0-9[:punct:]]+` + // other scripts and symbols
+	`)`)
+
+type englishAnalyzer struct {
+	preprocessor *Preprocessor
+}
+
+// Returns an analyzer splitting each line into words and punctuations.
+// Every token keeps the whitespace following it in the source, so that joining tokens restores the sentence.
+func NewEnglishAnalyzer(optFns ...func(*analyzerConf)) *englishAnalyzer {
+	conf := newAnalyzerConf(optFns...)
+	return &englishAnalyzer{
+		preprocessor: conf.preprocessor,
+	}
+}
+
+func (a *englishAnalyzer) Analyze(text string) ([][]string, error) {
+	preprocessed := a.preprocessor.Process(text)
+
+	sentences := strings.Split(preprocessed, "\n")
+	res := make([][]string, 0, len(sentences))
+	for _, sentence := range sentences {
+		tokens := tokenizeEnglish(strings.TrimSpace(sentence))
+		if len(tokens) == 0 {
+			continue
+		}
+		res = append(res, tokens)
+	}
+	return res, nil
+}
+
+func tokenizeEnglish(sentence string) []string {
+	tokens := []string{}
+	rest := sentence
+	for len(rest) > 0 {
+		loc := englishTokenPattern.FindStringIndex(rest)
+		end := len(rest)
+		if loc != nil {
+			end = loc[1]
+		}
+		// attach trailing whitespaces
+		end = skipSpaces(rest, end)
+		tokens = append(tokens, rest[:end])
+		rest = rest[end:]
+	}
+	return tokens
+}
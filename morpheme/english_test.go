@@ -0,0 +1,71 @@
+package morpheme_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/paralleltree/markov-bot-go/morpheme"
+)
+
+func TestEnglishAnalyzer_Analyze_KeepsSpacingAndPunctuations(t *testing.T) {
+	cases := []struct {
+		input string
+		want  [][]string
+	}{
+		{
+			input: "The quick brown fox jumps over the lazy dog.",
+			want:  [][]string{{"The ", "quick ", "brown ", "fox ", "jumps ", "over ", "the ", "lazy ", "dog", "."}},
+		},
+		{
+			input: "I don't know, e.g. at 12:00 or 1,000.5 times",
+			want:  [][]string{{"I ", "don't ", "know", ", ", "e.g. ", "at ", "12:00 ", "or ", "1,000.5 ", "times"}},
+		},
+		{
+			input: "Wait... (really)",
+			want:  [][]string{{"Wait", "... ", "(", "really", ")"}},
+		},
+		{
+			input: "First line\n\nSecond line",
+			want:  [][]string{{"First ", "line"}, {"Second ", "line"}},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.input, func(t *testing.T) {
+			// arrange
+			analyzer := morpheme.NewEnglishAnalyzer(morpheme.WithoutPreprocessing())
+
+			// act
+			got, err := analyzer.Analyze(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// assert
+			if !reflect.DeepEqual(tt.want, got) {
+				t.Fatalf("unexpected result: want %q, but got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEnglishAnalyzer_Analyze_JoinedTokensRestoreSentence(t *testing.T) {
+	// arrange
+	input := "Don't   panic: it's only 3.14 (approx.) -- right?"
+	analyzer := morpheme.NewEnglishAnalyzer(morpheme.WithoutPreprocessing())
+
+	// act
+	got, err := analyzer.Analyze(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// assert
+	if len(got) != 1 {
+		t.Fatalf("unexpected sentences count: want %d, but got %d", 1, len(got))
+	}
+	if restored := strings.Join(got[0], ""); input != restored {
+		t.Fatalf("unexpected restored sentence: want %q, but got %q", input, restored)
+	}
+}
@@ -0,0 +1,41 @@
+package morpheme
+
+import (
+	"strings"
+
+	"github.com/rivo/uniseg"
+)
+
+type graphemeAnalyzer struct {
+	preprocessor *Preprocessor
+}
+
+// Returns an analyzer splitting each line into user-perceived characters (extended grapheme clusters),
+// so that emoji sequences joined with ZWJ or combining marks are kept intact.
+// This requires no external tokenizer and works for any language.
+func NewGraphemeAnalyzer(optFns ...func(*analyzerConf)) *graphemeAnalyzer {
+	conf := newAnalyzerConf(optFns...)
+	return &graphemeAnalyzer{
+		preprocessor: conf.preprocessor,
+	}
+}
+
+func (a *graphemeAnalyzer) Analyze(text string) ([][]string, error) {
+	preprocessed := a.preprocessor.Process(text)
+
+	sentences := strings.Split(preprocessed, "\n")
+	res := make([][]string, 0, len(sentences))
+	for _, sentence := range sentences {
+		sentence = strings.TrimSpace(sentence)
+		if sentence == "" {
+			continue
+		}
+		graphemes := make([]string, 0, len(sentence))
+		g := uniseg.NewGraphemes(sentence)
+		for g.Next() {
+			graphemes = append(graphemes, g.Str())
+		}
+		res = append(res, graphemes)
+	}
+	return res, nil
+}
@@ -0,0 +1,55 @@
+package morpheme_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/paralleltree/markov-bot-go/morpheme"
+)
+
+func TestGraphemeAnalyzer_Analyze_SplitsIntoGraphemes(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  [][]string
+	}{
+		{
+			name:  "japanese",
+			input: "家族です",
+			want:  [][]string{{"家", "族", "で", "す"}},
+		},
+		{
+			name:  "emoji zwj sequence and flags",
+			input: "👨‍👩‍👧 🇯🇵!",
+			want:  [][]string{{"👨‍👩‍👧", " ", "🇯🇵", "！"}},
+		},
+		{
+			name:  "combining marks",
+			input: "e\u0301te\u0301",
+			want:  [][]string{{"e\u0301", "t", "e\u0301"}},
+		},
+		{
+			name:  "sentences",
+			input: "はい。いいえ",
+			want:  [][]string{{"は", "い", "。"}, {"い", "い", "え"}},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			analyzer := morpheme.NewGraphemeAnalyzer()
+
+			// act
+			got, err := analyzer.Analyze(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// assert
+			if !reflect.DeepEqual(tt.want, got) {
+				t.Fatalf("unexpected result: want %q, but got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -6,20 +6,25 @@ import (
 	"os/exec"
 	"path"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type mecabAnalyzer struct {
-	dicType string
+	dicType      string
+	preprocessor *Preprocessor
 }
 
-func NewMecabAnalyzer(dicType string) *mecabAnalyzer {
+func NewMecabAnalyzer(dicType string, optFns ...func(*analyzerConf)) *mecabAnalyzer {
+	conf := newAnalyzerConf(optFns...)
 	return &mecabAnalyzer{
-		dicType: dicType,
+		dicType:      dicType,
+		preprocessor: conf.preprocessor,
 	}
 }
 
 func (a *mecabAnalyzer) Analyze(text string) ([][]string, error) {
-	preprocessed := PreprocessSentence(text)
+	preprocessed := a.preprocessor.Process(text)
 
 	dicDir, err := resolveDicDir(a.dicType)
 	if err != nil {
@@ -59,7 +64,42 @@ func (a *mecabAnalyzer) Analyze(text string) ([][]string, error) {
 		res = append(res, filtered)
 	}
 
-	return res, nil
+	return attachTrailingSpaces(preprocessed, res), nil
+}
+
+// Restores whitespaces dropped by MeCab by aligning tokens with the source text.
+// Each token gets the spaces following it in the source, except the last token of a sentence.
+func attachTrailingSpaces(source string, sentences [][]string) [][]string {
+	cursor := 0
+	for _, words := range sentences {
+		for i, word := range words {
+			pos := strings.Index(source[cursor:], word)
+			if pos < 0 {
+				// the token does not appear in the source as is
+				continue
+			}
+			cursor += pos + len(word)
+			end := skipSpaces(source, cursor)
+			if i < len(words)-1 {
+				words[i] = word + source[cursor:end]
+			}
+			cursor = end
+		}
+	}
+	return sentences
+}
+
+// Returns the index of the first character which is not a space except line breaks.
+func skipSpaces(s string, start int) int {
+	i := start
+	for i < len(s) {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == '\n' || !unicode.IsSpace(r) {
+			break
+		}
+		i += size
+	}
+	return i
 }
 
 func resolveDicDir(dicType string) (string, error) {
@@ -0,0 +1,3 @@
+package morpheme
+
+var AttachTrailingSpaces = attachTrailingSpaces
@@ -0,0 +1,48 @@
+package morpheme_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/paralleltree/markov-bot-go/morpheme"
+)
+
+func TestAttachTrailingSpaces_RestoresSpacesFromSource(t *testing.T) {
+	cases := []struct {
+		name   string
+		source string
+		input  [][]string
+		want   [][]string
+	}{
+		{
+			name:   "english words",
+			source: "EZ DO DANCE",
+			input:  [][]string{{"EZ", "DO", "DANCE"}},
+			want:   [][]string{{"EZ ", "DO ", "DANCE"}},
+		},
+		{
+			name:   "mixed latin and japanese",
+			source: "12:00:50に出力された log です",
+			input:  [][]string{{"12", ":", "00", ":", "50", "に", "出力", "さ", "れ", "た", "log", "です"}},
+			want:   [][]string{{"12", ":", "00", ":", "50", "に", "出力", "さ", "れ", "た ", "log ", "です"}},
+		},
+		{
+			name:   "spaces at the end of sentence are dropped",
+			source: "こんにちは。 \nHello world \n",
+			input:  [][]string{{"こんにちは", "。"}, {"Hello", "world"}},
+			want:   [][]string{{"こんにちは", "。"}, {"Hello ", "world"}},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			got := morpheme.AttachTrailingSpaces(tt.source, tt.input)
+
+			// assert
+			if !reflect.DeepEqual(tt.want, got) {
+				t.Fatalf("unexpected result: want %q, but got %q", tt.want, got)
+			}
+		})
+	}
+}
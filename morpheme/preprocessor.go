@@ -1,24 +1,215 @@
 package morpheme
 
 import (
+	"fmt"
+	"html"
 	"regexp"
 	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
+)
+
+// PreprocessStep is a single normalization applied to a text before analyzing it.
+// Sentence splitting steps insert line breaks; analyzers treat each line as a sentence.
+type PreprocessStep func(text string) string
+
+// Names of the preprocessing steps which can be declared in the configuration.
+const (
+	StepSkipURL              = "skip_url"
+	StepNFKC                 = "nfkc"
+	StepWidth                = "width"
+	StepFullwidthPunctuation = "fullwidth_punctuation"
+	StepHTMLEntities         = "html_entities"
+	StepNBSPAsSpace          = "nbsp_as_space"
+	StepRemoveEmoji          = "remove_emoji"
+	StepJoinLines            = "join_lines"
+	StepSplitJapanesePeriod  = "split_japanese_period"
+	StepSplitPeriod          = "split_period"
+	StepSplitExclamation     = "split_exclamation"
+	StepLowercase            = "lowercase"
 )
 
+var preprocessSteps = map[string]PreprocessStep{
+	StepSkipURL:              skipURL,
+	StepNFKC:                 norm.NFKC.String,
+	StepWidth:                width.Fold.String,
+	StepFullwidthPunctuation: fullwidthPunctuation,
+	StepHTMLEntities:         decodeHTMLEntities,
+	StepNBSPAsSpace:          nbspAsSpace,
+	StepRemoveEmoji:          removeEmoji,
+	StepJoinLines:            joinLines,
+	StepSplitJapanesePeriod:  splitJapanesePeriod,
+	StepSplitPeriod:          splitPeriod,
+	StepSplitExclamation:     splitExclamation,
+	StepLowercase:            strings.ToLower,
+}
+
+// DefaultPreprocessSteps is the pipeline used when no steps are configured.
+// Sentences are split before converting punctuations, so that "．" does not end a sentence;
+// placing fullwidth_punctuation before split_japanese_period splits there as well.
+var DefaultPreprocessSteps = []string{
+	StepSkipURL,
+	StepSplitJapanesePeriod,
+	StepFullwidthPunctuation,
+	StepHTMLEntities,
+}
+
+type Preprocessor struct {
+	steps []PreprocessStep
+}
+
+// Builds a preprocessor applying named steps in the given order.
+func NewPreprocessor(stepNames ...string) (*Preprocessor, error) {
+	steps := make([]PreprocessStep, 0, len(stepNames))
+	for _, name := range stepNames {
+		step, ok := preprocessSteps[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown preprocess step: %s", name)
+		}
+		steps = append(steps, step)
+	}
+	return &Preprocessor{steps: steps}, nil
+}
+
+func DefaultPreprocessor() *Preprocessor {
+	p, err := NewPreprocessor(DefaultPreprocessSteps...)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func (p *Preprocessor) Process(text string) string {
+	for _, step := range p.steps {
+		if text == "" {
+			break
+		}
+		text = step(text)
+	}
+	return text
+}
+
 func PreprocessSentence(sentence string) string {
-	if regexp.MustCompile("https?://").MatchString(sentence) {
+	return DefaultPreprocessor().Process(sentence)
+}
+
+var urlPattern = regexp.MustCompile("https?://")
+
+// Drops the whole text if it contains URL.
+func skipURL(text string) string {
+	if urlPattern.MatchString(text) {
 		return ""
 	}
-	replacer := strings.NewReplacer(
-		"!", "！",
-		"?", "？",
-		"，", "、",
-		"．", "。",
-		"。", "。\n",
-		"&lt;", "<",
-		"&gt;", ">",
-		"&amp;", "&",
-		"&nbsp;", "",
-	)
-	return replacer.Replace(sentence)
+	return text
+}
+
+var fullwidthPunctuationReplacer = strings.NewReplacer(
+	"!", "！",
+	"?", "？",
+	"，", "、",
+	"．", "。",
+)
+
+func fullwidthPunctuation(text string) string {
+	return fullwidthPunctuationReplacer.Replace(text)
+}
+
+// Decodes HTML entities except "&nbsp;", which is dropped.
+// Place nbsp_as_space before this step to replace it with a space instead.
+func decodeHTMLEntities(text string) string {
+	return html.UnescapeString(strings.ReplaceAll(text, "&nbsp;", ""))
+}
+
+var nbspReplacer = strings.NewReplacer(
+	"&nbsp;", " ",
+	"\u00a0", " ",
+)
+
+// Replaces non-breaking spaces and their entities with spaces, so that they separate words as spaces do.
+func nbspAsSpace(text string) string {
+	return nbspReplacer.Replace(text)
+}
+
+func removeEmoji(text string) string {
+	runes := []rune(text)
+	var b strings.Builder
+	for i, r := range runes {
+		if isEmojiComponent(r) {
+			continue
+		}
+		if unicode.Is(extendedPictographic, r) && (isEmojiPresentation(r) || (i+1 < len(runes) && runes[i+1] == 0xfe0f)) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Reports whether the pictograph is shown as emoji without the emoji variation selector.
+// Pictographs outside the BMP are shown as emoji on most platforms regardless of the property.
+// The others, e.g. "★", "☆" and "♪", are commonly used as text and kept.
+func isEmojiPresentation(r rune) bool {
+	return r > 0xffff || unicode.Is(emojiPresentation, r)
+}
+
+// Reports whether the rune only modifies or joins emoji.
+func isEmojiComponent(r rune) bool {
+	switch {
+	case r == 0x200d: // zero width joiner
+		return true
+	case r == 0x20e3: // combining enclosing keycap
+		return true
+	case 0xfe00 <= r && r <= 0xfe0f: // variation selectors
+		return true
+	case 0x1f1e6 <= r && r <= 0x1f1ff: // regional indicators
+		return true
+	case 0x1f3fb <= r && r <= 0x1f3ff: // skin tone modifiers
+		return true
+	case 0xe0020 <= r && r <= 0xe007f: // tag characters
+		return true
+	}
+	return false
+}
+
+func joinLines(text string) string {
+	return strings.Join(strings.FieldsFunc(text, func(r rune) bool { return r == '\n' || r == '\r' }), " ")
+}
+
+func splitJapanesePeriod(text string) string {
+	return strings.ReplaceAll(text, "。", "。\n")
+}
+
+func splitExclamation(text string) string {
+	return splitAfter(text, false, func(r rune) bool {
+		return r == '!' || r == '?' || r == '！' || r == '？'
+	})
+}
+
+// Splits after '.' only when it is followed by a space or the end of the text, so that "1.5" or "example.com" are kept.
+func splitPeriod(text string) string {
+	return splitAfter(text, true, func(r rune) bool { return r == '.' })
+}
+
+// Inserts line breaks after a run of terminators, swallowing spaces that follow it.
+func splitAfter(text string, needsSpace bool, isTerminator func(rune) bool) string {
+	runes := []rune(text)
+	var b strings.Builder
+	for i := 0; i < len(runes); i++ {
+		b.WriteRune(runes[i])
+		if !isTerminator(runes[i]) || (i+1 < len(runes) && isTerminator(runes[i+1])) {
+			continue
+		}
+		j := i + 1
+		for j < len(runes) && unicode.IsSpace(runes[j]) && runes[j] != '\n' {
+			j++
+		}
+		if needsSpace && j == i+1 && j < len(runes) {
+			continue
+		}
+		b.WriteByte('\n')
+		i = j - 1
+	}
+	return b.String()
 }
@@ -0,0 +1,129 @@
+package morpheme_test
+
+import (
+	"testing"
+
+	"github.com/paralleltree/markov-bot-go/morpheme"
+)
+
+func TestPreprocessor_Process_AppliesStepsInOrder(t *testing.T) {
+	cases := []struct {
+		name  string
+		steps []string
+		input string
+		want  string
+	}{
+		{
+			name:  "default steps",
+			steps: morpheme.DefaultPreprocessSteps,
+			input: "すごい!本当?&lt;b&gt;。次の文",
+			want:  "すごい！本当？<b>。\n次の文",
+		},
+		{
+			name:  "default steps do not split at fullwidth period",
+			steps: morpheme.DefaultPreprocessSteps,
+			input: "１．５倍。次の文",
+			want:  "１。５倍。\n次の文",
+		},
+		{
+			name:  "split at fullwidth period after converting it",
+			steps: []string{morpheme.StepFullwidthPunctuation, morpheme.StepSplitJapanesePeriod},
+			input: "終わり．次の文",
+			want:  "終わり。\n次の文",
+		},
+		{
+			name:  "skip text containing url",
+			steps: []string{morpheme.StepSkipURL, morpheme.StepLowercase},
+			input: "See https://example.com",
+			want:  "",
+		},
+		{
+			name:  "nfkc normalizes halfwidth katakana and fullwidth alphabets",
+			steps: []string{morpheme.StepNFKC},
+			input: "ｱｲｳＡＢＣ",
+			want:  "アイウABC",
+		},
+		{
+			name:  "width folds fullwidth alphabets",
+			steps: []string{morpheme.StepWidth},
+			input: "ＡＢＣ１２３",
+			want:  "ABC123",
+		},
+		{
+			name:  "remove emoji including zwj sequences",
+			steps: []string{morpheme.StepRemoveEmoji},
+			input: "家族👨‍👩‍👧です🎉",
+			want:  "家族です",
+		},
+		{
+			name:  "remove symbols shown as emoji",
+			steps: []string{morpheme.StepRemoveEmoji},
+			input: "晴れ☀️のち☔、✨",
+			want:  "晴れのち、",
+		},
+		{
+			name:  "remove pictographs shown as emoji outside miscellaneous symbols",
+			steps: []string{morpheme.StepRemoveEmoji},
+			input: "⭐️星⭐と⌚、1️⃣",
+			want:  "星と、1",
+		},
+		{
+			name:  "keep symbols used as text",
+			steps: []string{morpheme.StepRemoveEmoji},
+			input: "★☆♪♡©",
+			want:  "★☆♪♡©",
+		},
+		{
+			name:  "split english sentences",
+			steps: []string{morpheme.StepJoinLines, morpheme.StepSplitPeriod, morpheme.StepSplitExclamation},
+			input: "It costs 1.5 dollars. Wow!! Really?\nYes",
+			want:  "It costs 1.5 dollars.\nWow!!\nReally?\nYes",
+		},
+		{
+			name:  "html entities drop non-breaking space entities",
+			steps: []string{morpheme.StepHTMLEntities},
+			input: "10&nbsp;km&amp;&#39;",
+			want:  "10km&'",
+		},
+		{
+			name:  "non-breaking spaces replaced with spaces before decoding entities",
+			steps: []string{morpheme.StepNBSPAsSpace, morpheme.StepHTMLEntities},
+			input: "10\u00a0km&nbsp;&amp;&nbsp;5 km",
+			want:  "10 km & 5 km",
+		},
+		{
+			name:  "lowercase",
+			steps: []string{morpheme.StepLowercase},
+			input: "The Quick Fox",
+			want:  "the quick fox",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			p, err := morpheme.NewPreprocessor(tt.steps...)
+			if err != nil {
+				t.Fatalf("unexpected error while building preprocessor: %v", err)
+			}
+
+			// act
+			got := p.Process(tt.input)
+
+			// assert
+			if tt.want != got {
+				t.Fatalf("unexpected result: want %q, but got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNewPreprocessor_WithUnknownStep_ReturnsError(t *testing.T) {
+	// act
+	_, err := morpheme.NewPreprocessor("unknown_step")
+
+	// assert
+	if err == nil {
+		t.Fatalf("NewPreprocessor() should return error, but got nil")
+	}
+}
@@ -0,0 +1,90 @@
+package morpheme
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type Language string
+
+const (
+	LanguageJapanese Language = "ja"
+	LanguageEnglish  Language = "en"
+)
+
+type languageRoutingAnalyzer struct {
+	preprocessor *Preprocessor
+	analyzers    map[Language]MorphemeAnalyzer
+}
+
+// Returns an analyzer which detects the language of each sentence and delegates it to the analyzer for the language.
+// Texts are preprocessed by this analyzer, so the delegated analyzers should be built with WithoutPreprocessing.
+func NewLanguageRoutingAnalyzer(japanese, english MorphemeAnalyzer, optFns ...func(*analyzerConf)) *languageRoutingAnalyzer {
+	conf := newAnalyzerConf(optFns...)
+	return &languageRoutingAnalyzer{
+		preprocessor: conf.preprocessor,
+		analyzers: map[Language]MorphemeAnalyzer{
+			LanguageJapanese: japanese,
+			LanguageEnglish:  english,
+		},
+	}
+}
+
+func (a *languageRoutingAnalyzer) Analyze(text string) ([][]string, error) {
+	preprocessed := a.preprocessor.Process(text)
+
+	res := [][]string{}
+	// consecutive sentences in the same language are analyzed at once
+	flush := func(lang Language, sentences []string) error {
+		if len(sentences) == 0 {
+			return nil
+		}
+		result, err := a.analyzers[lang].Analyze(strings.Join(sentences, "\n"))
+		if err != nil {
+			return fmt.Errorf("analyze %s sentences: %w", lang, err)
+		}
+		res = append(res, result...)
+		return nil
+	}
+
+	var currentLang Language
+	buf := []string{}
+	for _, sentence := range strings.Split(preprocessed, "\n") {
+		sentence = strings.TrimSpace(sentence)
+		if sentence == "" {
+			continue
+		}
+		lang := DetectLanguage(sentence)
+		if lang != currentLang {
+			if err := flush(currentLang, buf); err != nil {
+				return nil, err
+			}
+			currentLang = lang
+			buf = buf[:0]
+		}
+		buf = append(buf, sentence)
+	}
+	if err := flush(currentLang, buf); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Detects the language of a sentence from its script.
+// Sentences containing kana or kanji are regarded as Japanese, and sentences containing only latin letters as English.
+func DetectLanguage(sentence string) Language {
+	hasLatin := false
+	for _, r := range sentence {
+		if unicode.In(r, unicode.Hiragana, unicode.Katakana, unicode.Han) {
+			return LanguageJapanese
+		}
+		if unicode.Is(unicode.Latin, r) {
+			hasLatin = true
+		}
+	}
+	if hasLatin {
+		return LanguageEnglish
+	}
+	return LanguageJapanese
+}
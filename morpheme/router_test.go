@@ -0,0 +1,80 @@
+package morpheme_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/paralleltree/markov-bot-go/morpheme"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	cases := []struct {
+		input string
+		want  morpheme.Language
+	}{
+		{input: "アルミ缶の上にあるミカン", want: morpheme.LanguageJapanese},
+		{input: "GetPostsFetcherの呼び出し", want: morpheme.LanguageJapanese},
+		{input: "The quick brown fox", want: morpheme.LanguageEnglish},
+		{input: "12:00:50", want: morpheme.LanguageJapanese},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.input, func(t *testing.T) {
+			// act
+			got := morpheme.DetectLanguage(tt.input)
+
+			// assert
+			if tt.want != got {
+				t.Fatalf("unexpected language: want %s, but got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLanguageRoutingAnalyzer_Analyze_RoutesSentencesByLanguage(t *testing.T) {
+	// arrange
+	japanese := &recordingAnalyzer{}
+	english := morpheme.NewEnglishAnalyzer(morpheme.WithoutPreprocessing())
+	p, err := morpheme.NewPreprocessor(morpheme.StepSplitJapanesePeriod, morpheme.StepSplitPeriod)
+	if err != nil {
+		t.Fatalf("unexpected error while building preprocessor: %v", err)
+	}
+	analyzer := morpheme.NewLanguageRoutingAnalyzer(japanese, english, morpheme.WithPreprocessor(p))
+
+	// act
+	got, err := analyzer.Analyze("こんにちは。今日は晴れ。Hello, world. See you. またね")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// assert
+	want := [][]string{
+		{"こんにちは。"},
+		{"今日は晴れ。"},
+		{"Hello", ", ", "world", "."},
+		{"See ", "you", "."},
+		{"またね"},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("unexpected result: want %q, but got %q", want, got)
+	}
+	wantJapaneseInputs := []string{"こんにちは。\n今日は晴れ。", "またね"}
+	if !reflect.DeepEqual(wantJapaneseInputs, japanese.inputs) {
+		t.Fatalf("unexpected inputs for japanese analyzer: want %q, but got %q", wantJapaneseInputs, japanese.inputs)
+	}
+}
+
+// Returns each line as a single token.
+type recordingAnalyzer struct {
+	inputs []string
+}
+
+func (a *recordingAnalyzer) Analyze(text string) ([][]string, error) {
+	a.inputs = append(a.inputs, text)
+	res := [][]string{}
+	for _, line := range strings.Split(text, "\n") {
+		res = append(res, []string{line})
+	}
+	return res, nil
+}
@@ -1,32 +1,49 @@
 package persistence
 
 import (
-	"bytes"
 	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
 	"time"
+
+	"github.com/klauspost/compress/zstd"
 )
 
 type compressedStore struct {
-	store PersistentStore
+	store     PersistentStore
+	newWriter func(w io.Writer) (io.WriteCloser, error)
+	newReader func(r io.Reader) (io.ReadCloser, error)
 }
 
+// Returns a store compressing data with gzip.
+// The store implements ConditionalLoader only if the underlying store does.
 func NewCompressedStore(store PersistentStore) PersistentStore {
-	return &compressedStore{
-		store: store,
+	return newCompressedStore(store, newGzipWriter, newGzipReader)
+}
+
+// Returns a store compressing data with zstd, which is faster and smaller than gzip.
+// The store implements ConditionalLoader only if the underlying store does.
+func NewZstdCompressedStore(store PersistentStore) PersistentStore {
+	return newCompressedStore(store, newZstdWriter, newZstdReader)
+}
+
+func newCompressedStore(store PersistentStore, newWriter func(w io.Writer) (io.WriteCloser, error), newReader func(r io.Reader) (io.ReadCloser, error)) PersistentStore {
+	s := &compressedStore{
+		store:     store,
+		newWriter: newWriter,
+		newReader: newReader,
+	}
+	if cl, ok := store.(ConditionalLoader); ok {
+		return &conditionalCompressedStore{compressedStore: s, loader: cl}
 	}
+	return s
 }
 
 func (s *compressedStore) Load(ctx context.Context) ([]byte, error) {
-	raw, err := s.store.Load(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("load data: %w", err)
-	}
-	r, err := gzip.NewReader(bytes.NewReader(raw))
+	r, err := s.Open(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("new gzip reader: %w", err)
+		return nil, err
 	}
 	defer r.Close()
 
@@ -37,30 +54,140 @@ func (s *compressedStore) Load(ctx context.Context) ([]byte, error) {
 	return body, nil
 }
 
+// Decompresses the data stream of the underlying store while reading.
+func (s *compressedStore) Open(ctx context.Context) (io.ReadCloser, error) {
+	raw, err := OpenStream(ctx, s.store)
+	if err != nil {
+		return nil, fmt.Errorf("load data: %w", err)
+	}
+	return s.decompress(raw)
+}
+
+// Compressed store over a store implementing ConditionalLoader.
+type conditionalCompressedStore struct {
+	*compressedStore
+	loader ConditionalLoader
+}
+
+// Opens data only if modified, with the ETag of the underlying store.
+func (s *conditionalCompressedStore) OpenIfModified(ctx context.Context, etag string) (io.ReadCloser, string, bool, error) {
+	raw, newETag, modified, err := s.loader.OpenIfModified(ctx, etag)
+	if err != nil || !modified {
+		return nil, newETag, modified, err
+	}
+	r, err := s.decompress(raw)
+	if err != nil {
+		return nil, "", false, err
+	}
+	return r, newETag, true, nil
+}
+
+func (s *compressedStore) decompress(raw io.ReadCloser) (io.ReadCloser, error) {
+	r, err := s.newReader(raw)
+	if err != nil {
+		raw.Close()
+		return nil, err
+	}
+	return &compressedReader{ReadCloser: r, raw: raw}, nil
+}
+
 func (s *compressedStore) ModTime(ctx context.Context) (time.Time, bool, error) {
 	return s.store.ModTime(ctx)
 }
 
 func (s *compressedStore) Save(ctx context.Context, data []byte) error {
-	buf := new(bytes.Buffer)
-	if err := compressStream(buf, data); err != nil {
-		return fmt.Errorf("compress stream: %w", err)
+	w, err := s.Create(ctx)
+	if err != nil {
+		return err
 	}
-
-	if err := s.store.Save(ctx, buf.Bytes()); err != nil {
+	if err := writeAll(w, data); err != nil {
 		return fmt.Errorf("save compressed data: %w", err)
 	}
-
 	return nil
 }
 
-func compressStream(w io.Writer, data []byte) error {
-	gw := gzip.NewWriter(w)
-	defer gw.Close()
+// Compresses data written to the writer into the underlying store.
+func (s *compressedStore) Create(ctx context.Context) (io.WriteCloser, error) {
+	raw, err := createStream(ctx, s.store)
+	if err != nil {
+		return nil, fmt.Errorf("create data: %w", err)
+	}
+	w, err := s.newWriter(raw)
+	if err != nil {
+		abortStream(raw)
+		return nil, err
+	}
+	return &compressedWriter{WriteCloser: w, raw: raw}, nil
+}
 
-	if _, err := gw.Write(data); err != nil {
-		return fmt.Errorf("write compressed stream: %w", err)
+func (s *compressedStore) Delete(ctx context.Context) error {
+	d, ok := s.store.(Deleter)
+	if !ok {
+		return fmt.Errorf("underlying store does not support deletion")
 	}
+	return d.Delete(ctx)
+}
 
+// Closes the underlying stream as well as the decompressor.
+type compressedReader struct {
+	io.ReadCloser
+	raw io.ReadCloser
+}
+
+func (r *compressedReader) Close() error {
+	err := r.ReadCloser.Close()
+	if rawErr := r.raw.Close(); err == nil {
+		err = rawErr
+	}
+	return err
+}
+
+// Flushes the compressor and then saves the underlying stream on closing.
+type compressedWriter struct {
+	io.WriteCloser
+	raw io.WriteCloser
+}
+
+func (w *compressedWriter) Close() error {
+	if err := w.WriteCloser.Close(); err != nil {
+		abortStream(w.raw)
+		return fmt.Errorf("close compressed stream: %w", err)
+	}
+	return w.raw.Close()
+}
+
+func (w *compressedWriter) Abort() error {
+	w.WriteCloser.Close()
+	if a, ok := w.raw.(Aborter); ok {
+		return a.Abort()
+	}
 	return nil
 }
+
+func newGzipWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func newGzipReader(r io.Reader) (io.ReadCloser, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("new gzip reader: %w", err)
+	}
+	return gr, nil
+}
+
+func newZstdWriter(w io.Writer) (io.WriteCloser, error) {
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return nil, fmt.Errorf("new zstd writer: %w", err)
+	}
+	return zw, nil
+}
+
+func newZstdReader(r io.Reader) (io.ReadCloser, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("new zstd reader: %w", err)
+	}
+	return zr.IOReadCloser(), nil
+}
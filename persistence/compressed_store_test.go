@@ -0,0 +1,57 @@
+package persistence_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/paralleltree/markov-bot-go/persistence"
+)
+
+func TestNewCompressedStore_ImplementsConditionalLoaderOnlyIfUnderlyingStoreDoes(t *testing.T) {
+	cases := []struct {
+		name  string
+		store func(t *testing.T) persistence.PersistentStore
+		want  bool
+	}{
+		{
+			name: "gzip+memory",
+			store: func(t *testing.T) persistence.PersistentStore {
+				return persistence.NewCompressedStore(persistence.NewMemoryStore())
+			},
+			want: true,
+		},
+		{
+			name: "gzip+file",
+			store: func(t *testing.T) persistence.PersistentStore {
+				return persistence.NewCompressedStore(persistence.NewFileStore(filepath.Join(t.TempDir(), "model")))
+			},
+			want: false,
+		},
+		{
+			name: "zstd+memory",
+			store: func(t *testing.T) persistence.PersistentStore {
+				return persistence.NewZstdCompressedStore(persistence.NewMemoryStore())
+			},
+			want: true,
+		},
+		{
+			name: "zstd+file",
+			store: func(t *testing.T) persistence.PersistentStore {
+				return persistence.NewZstdCompressedStore(persistence.NewFileStore(filepath.Join(t.TempDir(), "model")))
+			},
+			want: false,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			_, got := tt.store(t).(persistence.ConditionalLoader)
+
+			// assert
+			if tt.want != got {
+				t.Errorf("unexpected implementation of ConditionalLoader: want %v, but got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package persistence
+
+import (
+	"context"
+)
+
+type fileLocker struct {
+	path string
+}
+
+// Returns an advisory lock on the file at path, which is created if needed.
+// Processes on the same machine sharing the path exclude each other.
+func NewFileLocker(path string) Locker {
+	return &fileLocker{
+		path: path,
+	}
+}
+
+func (l *fileLocker) TryLock(ctx context.Context) (func() error, error) {
+	return tryLockFile(l.path)
+}
@@ -0,0 +1,34 @@
+//go:build !unix
+
+package persistence
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Locks by creating the file exclusively. Unlike flock(2), the lock remains if the process crashes,
+// and the file must be removed by hand.
+func tryLockFile(path string) (func() error, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil, ErrLocked
+		}
+		return nil, fmt.Errorf("create lock file: %w", err)
+	}
+	f.Close()
+	unlock := func() error {
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("remove lock file: %w", err)
+		}
+		return nil
+	}
+	return unlock, nil
+}
+
+// Directories cannot be synced on every platform, so renamed files rely on the OS to be flushed.
+func syncDir(dir string) error {
+	return nil
+}
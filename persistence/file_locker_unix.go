@@ -0,0 +1,44 @@
+//go:build unix
+
+package persistence
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// Locks the file with flock(2), which is released by the OS even if the process crashes.
+func tryLockFile(path string) (func() error, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open lock file: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, ErrLocked
+		}
+		return nil, fmt.Errorf("flock: %w", err)
+	}
+	unlock := func() error {
+		// the lock file is left to avoid racing with processes which have opened it
+		defer f.Close()
+		if err := syscall.Flock(int(f.Fd()), syscall.LOCK_UN); err != nil {
+			return fmt.Errorf("unlock: %w", err)
+		}
+		return nil
+	}
+	return unlock, nil
+}
+
+// Flushes the directory entry so that a renamed file survives a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("open directory: %w", err)
+	}
+	defer d.Close()
+	return d.Sync()
+}
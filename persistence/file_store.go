@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"time"
 )
 
@@ -44,15 +45,95 @@ func (s *fileStore) ModTime(ctx context.Context) (time.Time, bool, error) {
 	return stat.ModTime(), true, nil
 }
 
+func (s *fileStore) Delete(ctx context.Context) error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove file: %w", err)
+	}
+	return nil
+}
+
+func (s *fileStore) Open(ctx context.Context) (io.ReadCloser, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	return f, nil
+}
+
 func (s *fileStore) Save(ctx context.Context, data []byte) error {
-	f, err := os.Create(s.path)
+	w, err := s.Create(ctx)
 	if err != nil {
-		return fmt.Errorf("create file: %w", err)
+		return err
 	}
-	defer f.Close()
+	return writeAll(w, data)
+}
+
+// Returns a writer to a temporary file in the same directory, which is renamed over the file on closing,
+// so that readers never see a partially written file even if the process crashes.
+func (s *fileStore) Create(ctx context.Context) (io.WriteCloser, error) {
+	dir, base := filepath.Split(s.path)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, "."+base+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("create temporary file: %w", err)
+	}
+	return &fileWriter{File: f, path: s.path, dir: dir}, nil
+}
+
+type fileWriter struct {
+	*os.File
+	path string
+	dir  string
+}
+
+func (w *fileWriter) Write(p []byte) (int, error) {
+	n, err := w.File.Write(p)
+	if err != nil {
+		return n, fmt.Errorf("write to file: %w", err)
+	}
+	return n, nil
+}
+
+func (w *fileWriter) Close() error {
+	// removes the temporary file unless it is renamed
+	renamed := false
+	defer func() {
+		if !renamed {
+			w.Abort()
+		}
+	}()
+
+	// temporary files are created with 0600, so keep the mode of the existing file
+	mode := os.FileMode(0644)
+	if stat, err := os.Stat(w.path); err == nil {
+		mode = stat.Mode().Perm()
+	}
+	if err := w.Chmod(mode); err != nil {
+		return fmt.Errorf("change file mode: %w", err)
+	}
+	if err := w.Sync(); err != nil {
+		return fmt.Errorf("sync file: %w", err)
+	}
+	if err := w.File.Close(); err != nil {
+		return fmt.Errorf("close file: %w", err)
+	}
+	if err := os.Rename(w.Name(), w.path); err != nil {
+		return fmt.Errorf("rename file: %w", err)
+	}
+	renamed = true
+	if err := syncDir(w.dir); err != nil {
+		return fmt.Errorf("sync directory: %w", err)
+	}
+	return nil
+}
 
-	if _, err := f.Write(data); err != nil {
-		return fmt.Errorf("write to file: %w", err)
+// Removes the temporary file without replacing the file.
+func (w *fileWriter) Abort() error {
+	w.File.Close()
+	if err := os.Remove(w.Name()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove temporary file: %w", err)
 	}
 	return nil
 }
@@ -0,0 +1,63 @@
+package persistence_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paralleltree/markov-bot-go/persistence"
+)
+
+func TestFileStore_Save_ReplacesFileWithoutLeavingTemporaryFiles(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "model")
+	if err := os.WriteFile(path, []byte("old content which is longer"), 0640); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	store := persistence.NewFileStore(path)
+
+	// act
+	err := store.Save(ctx, []byte("new"))
+
+	// assert
+	if err != nil {
+		t.Fatalf("Save() should not return error, but got: %v", err)
+	}
+	got, err := store.Load(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(got) != "new" {
+		t.Fatalf("unexpected content: %q", got)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("temporary files should be removed, but got %d entries", len(entries))
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stat.Mode().Perm() != 0640 {
+		t.Fatalf("file mode should be kept, but got %v", stat.Mode().Perm())
+	}
+}
+
+func TestFileStore_Save_WhenDirectoryDoesNotExist_ReturnsError(t *testing.T) {
+	// arrange
+	store := persistence.NewFileStore(filepath.Join(t.TempDir(), "missing", "model"))
+
+	// act
+	err := store.Save(context.Background(), []byte("data"))
+
+	// assert
+	if err == nil {
+		t.Fatalf("Save() should return error")
+	}
+}
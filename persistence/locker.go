@@ -0,0 +1,39 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrLocked is returned when the lock is held by another owner.
+var ErrLocked = errors.New("locked by another owner")
+
+// Locker is a mutual exclusion lock shared between processes.
+type Locker interface {
+	// Acquires the lock without waiting. Returns ErrLocked if another owner holds it.
+	// The returned function releases the lock.
+	TryLock(ctx context.Context) (func() error, error)
+}
+
+// Interval to retry acquiring a lock held by another owner.
+const lockRetryInterval = 100 * time.Millisecond
+
+// Acquires the lock, waiting until another owner releases it or ctx is done.
+func Lock(ctx context.Context, l Locker) (func() error, error) {
+	for {
+		unlock, err := l.TryLock(ctx)
+		if err == nil {
+			return unlock, nil
+		}
+		if !errors.Is(err, ErrLocked) {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("wait for lock: %w", ctx.Err())
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
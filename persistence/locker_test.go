@@ -0,0 +1,81 @@
+package persistence_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/paralleltree/markov-bot-go/persistence"
+)
+
+func TestLocker_TryLock_ExcludesOtherOwnersUntilReleased(t *testing.T) {
+	cases := []struct {
+		name      string
+		newLocker func(t *testing.T) (persistence.Locker, persistence.Locker)
+	}{
+		{
+			name: "file",
+			newLocker: func(t *testing.T) (persistence.Locker, persistence.Locker) {
+				path := filepath.Join(t.TempDir(), "model.lock")
+				return persistence.NewFileLocker(path), persistence.NewFileLocker(path)
+			},
+		},
+		{
+			name: "memory",
+			newLocker: func(t *testing.T) (persistence.Locker, persistence.Locker) {
+				l := persistence.NewMemoryLocker()
+				return l, l
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			ctx := context.Background()
+			owner, other := tt.newLocker(t)
+			unlock, err := owner.TryLock(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// act
+			_, errWhileLocked := other.TryLock(ctx)
+			if err := unlock(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			unlockAgain, errAfterUnlock := other.TryLock(ctx)
+
+			// assert
+			if !errors.Is(errWhileLocked, persistence.ErrLocked) {
+				t.Fatalf("want ErrLocked while locked, but got: %v", errWhileLocked)
+			}
+			if errAfterUnlock != nil {
+				t.Fatalf("lock should be acquired after unlocked, but got: %v", errAfterUnlock)
+			}
+			if err := unlockAgain(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestLock_WaitsUntilContextIsDone(t *testing.T) {
+	// arrange
+	locker := persistence.NewMemoryLocker()
+	if _, err := locker.TryLock(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	// act
+	_, err := persistence.Lock(ctx, locker)
+
+	// assert
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want deadline exceeded, but got: %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package persistence
+
+import (
+	"context"
+	"sync"
+)
+
+type memoryLocker struct {
+	mu     sync.Mutex
+	locked bool
+}
+
+// Returns a lock within the process, mainly for tests.
+func NewMemoryLocker() *memoryLocker {
+	return &memoryLocker{}
+}
+
+func (l *memoryLocker) TryLock(ctx context.Context) (func() error, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.locked {
+		return nil, ErrLocked
+	}
+	l.locked = true
+	var once sync.Once
+	unlock := func() error {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.locked = false
+		})
+		return nil
+	}
+	return unlock, nil
+}
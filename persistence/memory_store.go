@@ -1,31 +1,81 @@
 package persistence
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// gives each memory store a unique prefix of ETags
+var memoryStoreCount atomic.Int64
+
 type memoryStore struct {
+	mu      sync.Mutex
+	id      int64
 	content []byte
 	modTime time.Time
+	// incremented on saving and deleting to give ETags
+	revision int
 }
 
 func NewMemoryStore() *memoryStore {
 	return &memoryStore{
+		id:      memoryStoreCount.Add(1),
 		content: []byte{},
 		modTime: time.Now(),
 	}
 }
 
 func (m *memoryStore) Load(ctx context.Context) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	return m.content, nil
 }
 
+func (m *memoryStore) Open(ctx context.Context) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return io.NopCloser(bytes.NewReader(m.content)), nil
+}
+
 func (m *memoryStore) ModTime(ctx context.Context) (time.Time, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	return m.modTime, len(m.content) > 0, nil
 }
 
+func (m *memoryStore) OpenIfModified(ctx context.Context, etag string) (io.ReadCloser, string, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	current := fmt.Sprintf("%d-%d", m.id, m.revision)
+	if etag == current {
+		return nil, etag, false, nil
+	}
+	return io.NopCloser(bytes.NewReader(m.content)), current, true, nil
+}
+
 func (m *memoryStore) Save(ctx context.Context, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.content = data
+	m.revision++
+	return nil
+}
+
+func (m *memoryStore) Create(ctx context.Context) (io.WriteCloser, error) {
+	return &bufferedWriter{save: func(data []byte) error {
+		return m.Save(ctx, data)
+	}}, nil
+}
+
+func (m *memoryStore) Delete(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.content = []byte{}
+	m.revision++
 	return nil
 }
@@ -0,0 +1,69 @@
+package persistence_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/paralleltree/markov-bot-go/persistence"
+)
+
+func TestMemoryStore_OpenIfModified_GivesETagsUniqueAcrossStores(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	storeA := persistence.NewMemoryStore()
+	storeB := persistence.NewMemoryStore()
+	if err := storeA.Save(ctx, []byte("apple")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := storeB.Save(ctx, []byte("banana")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, etag, _, err := storeA.OpenIfModified(ctx, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// act
+	r, _, modified, err := storeB.OpenIfModified(ctx, etag)
+
+	// assert
+	if err != nil {
+		t.Fatalf("OpenIfModified() should not return error, but got: %v", err)
+	}
+	if !modified {
+		t.Fatalf("OpenIfModified() should not accept the ETag of another store")
+	}
+	if got := readStream(t, r); got != "banana" {
+		t.Errorf("unexpected data: want %q, but got %q", "banana", got)
+	}
+}
+
+func TestMemoryStore_OpenIfModified_AfterDelete_ReportsModified(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	store := persistence.NewMemoryStore()
+	if err := store.Save(ctx, []byte("apple")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, etag, _, err := store.OpenIfModified(ctx, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := store.Delete(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// act
+	r, _, modified, err := store.OpenIfModified(ctx, etag)
+
+	// assert
+	if err != nil {
+		t.Fatalf("OpenIfModified() should not return error, but got: %v", err)
+	}
+	if !modified {
+		t.Fatalf("OpenIfModified() should report the deleted data as modified")
+	}
+	if got := readStream(t, r); got != "" {
+		t.Errorf("unexpected data: want empty, but got %q", got)
+	}
+}
@@ -0,0 +1,275 @@
+package persistence
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Schemes of store URLs.
+const (
+	SchemeFile   = "file"
+	SchemeS3     = "s3"
+	SchemeMemory = "mem"
+)
+
+// Modifiers appended to schemes with "+" to compress data.
+const (
+	ModifierGzip = "gzip"
+	ModifierZstd = "zstd"
+)
+
+// Opens the store at the URL:
+//
+//   - file:///path/to/file, or a path without scheme
+//   - s3://bucket/key?region=ap-northeast-1, where the region defaults to the environment.
+//     For S3-compatible services, endpoint, path_style and profile are also accepted,
+//     e.g. s3://bucket/key?endpoint=http://localhost:9000&path_style=true
+//     Static credentials can be given as the user info, e.g. s3://ACCESS_KEY_ID:SECRET_ACCESS_KEY@bucket/key
+//   - mem://name, shared by stores with the same name in the process
+//
+// Appending modifiers to the scheme compresses data, e.g. s3+gzip://bucket/model or file+zstd:///var/model.
+func Open(rawURL string) (PersistentStore, error) {
+	u, modifiers, err := parseStoreURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var store PersistentStore
+	switch u.Scheme {
+	case "", SchemeFile:
+		path, err := filePath(u)
+		if err != nil {
+			return nil, err
+		}
+		store = NewFileStore(path)
+	case SchemeS3:
+		bucket, key, region, opts, err := s3Location(u)
+		if err != nil {
+			return nil, err
+		}
+		if store, err = NewS3Store(region, bucket, key, opts...); err != nil {
+			return nil, err
+		}
+	case SchemeMemory:
+		store = namedMemoryStores.get(u.Host + u.Path)
+	default:
+		return nil, fmt.Errorf("unsupported scheme: %s", u.Scheme)
+	}
+
+	for _, m := range modifiers {
+		switch m {
+		case ModifierGzip:
+			store = NewCompressedStore(store)
+		case ModifierZstd:
+			store = NewZstdCompressedStore(store)
+		default:
+			return nil, fmt.Errorf("unsupported modifier: %s", m)
+		}
+	}
+	return store, nil
+}
+
+// Names of files under the directory or the prefix of versioned stores.
+const (
+	VersionManifestName = "manifest.json"
+	versionSuffix       = ".gz"
+)
+
+// Opens the versioned store under the directory or the prefix at the URL of the same form as Open, without modifiers,
+// e.g. s3://bucket/bot/models?region=ap-northeast-1 or a path of a directory.
+// The manifest is saved as manifest.json, and each version as <ID>.gz compressed with gzip.
+func OpenVersioned(rawURL string, optFns ...func(*versionedStoreConf)) (*versionedStore, error) {
+	u, modifiers, err := parseStoreURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if len(modifiers) > 0 {
+		return nil, fmt.Errorf("modifiers are not supported by versioned stores: %s", u.Scheme)
+	}
+
+	var storeAt func(name string) PersistentStore
+	switch u.Scheme {
+	case "", SchemeFile:
+		dir, err := filePath(u)
+		if err != nil {
+			return nil, err
+		}
+		storeAt = func(name string) PersistentStore {
+			return NewFileStore(filepath.Join(dir, name))
+		}
+	case SchemeS3:
+		bucket, prefix, region, opts, err := s3Location(u)
+		if err != nil {
+			return nil, err
+		}
+		// stores of versions share a session
+		provider, err := NewS3StoreProvider(region, bucket, opts...)
+		if err != nil {
+			return nil, err
+		}
+		storeAt = func(name string) PersistentStore {
+			return provider(strings.TrimSuffix(prefix, "/") + "/" + name)
+		}
+	case SchemeMemory:
+		storeAt = func(name string) PersistentStore {
+			return namedMemoryStores.get(strings.TrimSuffix(u.Host+u.Path, "/") + "/" + name)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported scheme: %s", u.Scheme)
+	}
+
+	return NewVersionedStore(
+		storeAt(VersionManifestName),
+		func(id string) PersistentStore {
+			return NewCompressedStore(storeAt(id + versionSuffix))
+		},
+		optFns...,
+	), nil
+}
+
+// Opens the lock at the URL of the same form as Open, without modifiers.
+// The TTL is used by locks which expire, such as ones on S3.
+func OpenLocker(rawURL string, ttl time.Duration) (Locker, error) {
+	u, modifiers, err := parseStoreURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if len(modifiers) > 0 {
+		return nil, fmt.Errorf("modifiers are not supported by locks: %s", u.Scheme)
+	}
+
+	switch u.Scheme {
+	case "", SchemeFile:
+		path, err := filePath(u)
+		if err != nil {
+			return nil, err
+		}
+		return NewFileLocker(path), nil
+	case SchemeS3:
+		bucket, key, region, opts, err := s3Location(u)
+		if err != nil {
+			return nil, err
+		}
+		return NewS3Locker(region, bucket, key, ttl, opts...)
+	case SchemeMemory:
+		return namedMemoryLockers.get(u.Host + u.Path), nil
+	default:
+		return nil, fmt.Errorf("unsupported scheme: %s", u.Scheme)
+	}
+}
+
+// Appends the suffix to the path or the key of the URL, to derive locations next to it.
+// Modifiers of the scheme are removed, e.g. s3+gzip://bucket/model and ".lock" give s3://bucket/model.lock.
+func AppendToKey(rawURL, suffix string) (string, error) {
+	if !isURL(rawURL) {
+		return rawURL + suffix, nil
+	}
+	u, _, err := parseStoreURL(rawURL)
+	if err != nil {
+		return "", err
+	}
+	u.Path += suffix
+	return u.String(), nil
+}
+
+// Reports whether the value is a URL rather than a path, which can contain characters such as "#" or "%".
+func isURL(rawURL string) bool {
+	return strings.Contains(rawURL, "://")
+}
+
+// Parses the URL and splits modifiers from its scheme. Values other than URLs are regarded as paths.
+func parseStoreURL(rawURL string) (*url.URL, []string, error) {
+	if !isURL(rawURL) {
+		return &url.URL{Path: rawURL}, nil, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse url: %w", err)
+	}
+	scheme, modifiers, _ := strings.Cut(u.Scheme, "+")
+	u.Scheme = scheme
+	if modifiers == "" {
+		return u, nil, nil
+	}
+	return u, strings.Split(modifiers, "+"), nil
+}
+
+func filePath(u *url.URL) (string, error) {
+	if u.Host != "" && u.Host != "localhost" {
+		return "", fmt.Errorf("file url must not have a host: %s", u.Host)
+	}
+	if len(u.Query()) > 0 {
+		return "", fmt.Errorf("file url does not accept parameters: %s", u.RawQuery)
+	}
+	return u.Path, nil
+}
+
+// Returns the bucket, the key, the region and options given by parameters of the URL.
+func s3Location(u *url.URL) (string, string, string, []func(*s3Conf), error) {
+	key := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || key == "" {
+		return "", "", "", nil, fmt.Errorf("s3 url must have a bucket and a key")
+	}
+	opts := []func(*s3Conf){}
+	if u.User != nil {
+		secretAccessKey, ok := u.User.Password()
+		if u.User.Username() == "" || !ok {
+			return "", "", "", nil, fmt.Errorf("credentials of s3 url must have both an access key id and a secret access key")
+		}
+		opts = append(opts, WithS3StaticCredentials(u.User.Username(), secretAccessKey, ""))
+	}
+	query := u.Query()
+	for k := range query {
+		v := query.Get(k)
+		switch k {
+		case "region":
+		case "endpoint":
+			opts = append(opts, WithS3Endpoint(v))
+		case "path_style":
+			pathStyle, err := strconv.ParseBool(v)
+			if err != nil {
+				return "", "", "", nil, fmt.Errorf("parse path_style: %w", err)
+			}
+			opts = append(opts, WithS3PathStyle(pathStyle))
+		case "profile":
+			opts = append(opts, WithS3Profile(v))
+		default:
+			return "", "", "", nil, fmt.Errorf("unsupported parameter of s3 url: %s", k)
+		}
+	}
+	return u.Host, key, query.Get("region"), opts, nil
+}
+
+type namedRegistry[T any] struct {
+	mu    sync.Mutex
+	items map[string]T
+	new   func() T
+}
+
+func (r *namedRegistry[T]) get(name string) T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	item, ok := r.items[name]
+	if !ok {
+		item = r.new()
+		r.items[name] = item
+	}
+	return item
+}
+
+var (
+	namedMemoryStores = &namedRegistry[*memoryStore]{
+		items: map[string]*memoryStore{},
+		new:   NewMemoryStore,
+	}
+	namedMemoryLockers = &namedRegistry[*memoryLocker]{
+		items: map[string]*memoryLocker{},
+		new:   NewMemoryLocker,
+	}
+)
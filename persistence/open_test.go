@@ -0,0 +1,195 @@
+package persistence_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/paralleltree/markov-bot-go/persistence"
+)
+
+func TestOpen_SavesAndLoadsData(t *testing.T) {
+	dir := t.TempDir()
+	cases := []struct {
+		name string
+		url  string
+		// the file written by the store, and the prefix of its content
+		path       string
+		wantPrefix []byte
+	}{
+		{name: "plain path", url: filepath.Join(dir, "plain"), path: filepath.Join(dir, "plain"), wantPrefix: []byte("data")},
+		{name: "plain path with url characters", url: filepath.Join(dir, "#1?a%20b"), path: filepath.Join(dir, "#1?a%20b"), wantPrefix: []byte("data")},
+		{name: "file", url: "file://" + filepath.Join(dir, "file"), path: filepath.Join(dir, "file"), wantPrefix: []byte("data")},
+		{name: "file with gzip", url: "file+gzip://" + filepath.Join(dir, "gzip"), path: filepath.Join(dir, "gzip"), wantPrefix: []byte{0x1f, 0x8b}},
+		{name: "file with zstd", url: "file+zstd://" + filepath.Join(dir, "zstd"), path: filepath.Join(dir, "zstd"), wantPrefix: []byte{0x28, 0xb5, 0x2f, 0xfd}},
+		{name: "memory", url: "mem://open-test"},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			ctx := context.Background()
+			store, err := persistence.Open(tt.url)
+			if err != nil {
+				t.Fatalf("Open() should not return error, but got: %v", err)
+			}
+
+			// act
+			if err := store.Save(ctx, []byte("data")); err != nil {
+				t.Fatalf("Save() should not return error, but got: %v", err)
+			}
+			// opens again to load the data saved by another store
+			reopened, err := persistence.Open(tt.url)
+			if err != nil {
+				t.Fatalf("Open() should not return error, but got: %v", err)
+			}
+			got, err := reopened.Load(ctx)
+
+			// assert
+			if err != nil {
+				t.Fatalf("Load() should not return error, but got: %v", err)
+			}
+			if string(got) != "data" {
+				t.Fatalf("unexpected data: %q", got)
+			}
+			if tt.path == "" {
+				return
+			}
+			raw, err := os.ReadFile(tt.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.HasPrefix(raw, tt.wantPrefix) {
+				t.Fatalf("unexpected content of file: %x", raw)
+			}
+		})
+	}
+}
+
+func TestOpen_WithInvalidURL_ReturnsError(t *testing.T) {
+	cases := []struct {
+		name string
+		url  string
+	}{
+		{name: "unsupported scheme", url: "ftp://example.com/model"},
+		{name: "unsupported modifier", url: "file+bzip2:///tmp/model"},
+		{name: "file with host", url: "file://example.com/model"},
+		{name: "s3 without key", url: "s3://bucket"},
+		{name: "s3 with unknown parameter", url: "s3://bucket/model?regoin=ap-northeast-1"},
+		{name: "s3 without secret access key", url: "s3://access-key@bucket/model"},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			_, err := persistence.Open(tt.url)
+
+			// assert
+			if err == nil {
+				t.Fatalf("Open() should return error")
+			}
+		})
+	}
+}
+
+func TestOpenVersioned_WithDirectory_SavesVersionsInIt(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := persistence.OpenVersioned(dir, persistence.WithKeepVersions(1))
+	if err != nil {
+		t.Fatalf("OpenVersioned() should not return error, but got: %v", err)
+	}
+
+	// act
+	for _, data := range []string{"v1", "v2"} {
+		if err := store.Save(ctx, []byte(data)); err != nil {
+			t.Fatalf("Save() should not return error, but got: %v", err)
+		}
+	}
+
+	// assert
+	m, err := store.Manifest(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	want := []string{m.Active + ".gz", persistence.VersionManifestName}
+	if !reflect.DeepEqual(want, names) {
+		t.Fatalf("unexpected files: want %v, but got %v", want, names)
+	}
+}
+
+func TestOpenVersioned_WithModifiers_ReturnsError(t *testing.T) {
+	// act
+	_, err := persistence.OpenVersioned("s3+gzip://bucket/bot/models")
+
+	// assert
+	if err == nil {
+		t.Fatalf("OpenVersioned() should return error")
+	}
+}
+
+func TestOpenLocker_WithMemoryURL_SharesLockByName(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	owner, err := persistence.OpenLocker("mem://open-locker-test", time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	other, err := persistence.OpenLocker("mem://open-locker-test", time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	unlock, err := owner.TryLock(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer unlock()
+
+	// act
+	_, err = other.TryLock(ctx)
+
+	// assert
+	if err == nil {
+		t.Fatalf("lock with the same name should be held")
+	}
+}
+
+func TestAppendToKey(t *testing.T) {
+	cases := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{name: "plain path", url: "data/model.gz", want: "data/model.gz.lock"},
+		{name: "plain path with url characters", url: "data/#1?a%20b", want: "data/#1?a%20b.lock"},
+		{name: "s3 with modifier and region", url: "s3+gzip://bucket/bot/model?region=ap-northeast-1", want: "s3://bucket/bot/model.lock?region=ap-northeast-1"},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			got, err := persistence.AppendToKey(tt.url, ".lock")
+
+			// assert
+			if err != nil {
+				t.Fatalf("AppendToKey() should not return error, but got: %v", err)
+			}
+			if tt.want != got {
+				t.Fatalf("want %s, but got %s", tt.want, got)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package persistence
 
 import (
 	"context"
+	"io"
 	"time"
 )
 
@@ -16,3 +17,49 @@ type PersistentStore interface {
 	// Saves given data stream.
 	Save(ctx context.Context, data []byte) error
 }
+
+// StreamStore is implemented by stores which can read and write data as streams,
+// without holding the whole data in memory.
+type StreamStore interface {
+	// Opens the data stream to read. The caller must close it.
+	Open(ctx context.Context) (io.ReadCloser, error)
+
+	// Returns a writer to replace the data, which is saved when the writer is closed.
+	// If the writer implements Aborter, aborting it instead discards the written data.
+	Create(ctx context.Context) (io.WriteCloser, error)
+}
+
+// Aborter is implemented by writers which can discard the data written so far.
+type Aborter interface {
+	// Discards the written data and releases the writer.
+	Abort() error
+}
+
+// ConditionalLoader is implemented by stores which can skip loading data which has not been modified.
+type ConditionalLoader interface {
+	// Opens the data stream unless its ETag equals the given one, and returns the stream with its ETag.
+	// The third returned value is false if the data is not modified, where the stream is nil.
+	// An empty ETag is returned if the data cannot be identified, and then it is always opened.
+	OpenIfModified(ctx context.Context, etag string) (io.ReadCloser, string, bool, error)
+}
+
+// Pinner is implemented by stores which can be pinned to refuse saving, e.g. versioned stores after rollback.
+type Pinner interface {
+	// Reports whether saving fails because the store is pinned.
+	Pinned(ctx context.Context) (bool, error)
+}
+
+// Reports whether the store is pinned. Stores not implementing Pinner are never pinned.
+func IsPinned(ctx context.Context, store PersistentStore) (bool, error) {
+	p, ok := store.(Pinner)
+	if !ok {
+		return false, nil
+	}
+	return p.Pinned(ctx)
+}
+
+// Deleter is implemented by stores which can delete their data.
+type Deleter interface {
+	// Deletes the data stream. Deleting data which does not exist is not an error.
+	Delete(ctx context.Context) error
+}
@@ -0,0 +1,190 @@
+package persistence_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/paralleltree/markov-bot-go/persistence"
+)
+
+type fakeS3Object struct {
+	body    []byte
+	etag    string
+	modTime time.Time
+}
+
+// fakeS3 is an in-process stand-in of S3 addressed by path style,
+// supporting the subset of the API used by the stores and the lockers.
+type fakeS3 struct {
+	mu       sync.Mutex
+	objects  map[string]fakeS3Object
+	etagSeq  int
+	requests []string
+	// access key IDs signing the requests
+	accessKeyIDs []string
+}
+
+// Starts a fake S3 server and returns its endpoint URL.
+func newFakeS3(t *testing.T) (*fakeS3, string) {
+	t.Helper()
+	f := &fakeS3{objects: map[string]fakeS3Object{}}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	return f, server.URL
+}
+
+func newFakeS3Store(t *testing.T, endpoint string, key string) persistence.PersistentStore {
+	t.Helper()
+	store, err := persistence.NewS3Store(
+		"us-east-1", "bucket", key,
+		persistence.WithS3Endpoint(endpoint),
+		persistence.WithS3PathStyle(true),
+		persistence.WithS3StaticCredentials("access-key", "secret-key", ""),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return store
+}
+
+func newFakeS3Locker(t *testing.T, endpoint string, key string, ttl time.Duration) persistence.Locker {
+	t.Helper()
+	locker, err := persistence.NewS3Locker(
+		"us-east-1", "bucket", key, ttl,
+		persistence.WithS3Endpoint(endpoint),
+		persistence.WithS3PathStyle(true),
+		persistence.WithS3StaticCredentials("access-key", "secret-key", ""),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return locker
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	f.requests = append(f.requests, r.Method+" "+name)
+	if _, credential, ok := strings.Cut(r.Header.Get("Authorization"), "Credential="); ok {
+		accessKeyID, _, _ := strings.Cut(credential, "/")
+		f.accessKeyIDs = append(f.accessKeyIDs, accessKeyID)
+	}
+	obj, exists := f.objects[name]
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if !exists {
+			writeFakeS3Error(w, r, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		if m := r.Header.Get("If-None-Match"); m != "" && m == obj.etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", obj.etag)
+		w.Header().Set("Last-Modified", obj.modTime.UTC().Format(http.TimeFormat))
+		body := obj.body
+		status := http.StatusOK
+		if start, end, ok := parseRange(r.Header.Get("Range"), len(body)); ok {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(body)))
+			body = body[start : end+1]
+			status = http.StatusPartialContent
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(status)
+		if r.Method == http.MethodGet {
+			w.Write(body)
+		}
+	case http.MethodPut:
+		if !f.satisfiesConditions(r, obj, exists) {
+			writeFakeS3Error(w, r, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeFakeS3Error(w, r, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		f.etagSeq++
+		obj = fakeS3Object{body: body, etag: fmt.Sprintf("\"%d\"", f.etagSeq), modTime: time.Now()}
+		f.objects[name] = obj
+		w.Header().Set("ETag", obj.etag)
+		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		if !f.satisfiesConditions(r, obj, exists) {
+			writeFakeS3Error(w, r, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+		delete(f.objects, name)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeFakeS3Error(w, r, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+func (f *fakeS3) requestLog() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string{}, f.requests...)
+}
+
+func (f *fakeS3) signedAccessKeyIDs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string{}, f.accessKeyIDs...)
+}
+
+func (f *fakeS3) has(name string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, ok := f.objects[name]
+	return ok
+}
+
+func (f *fakeS3) satisfiesConditions(r *http.Request, obj fakeS3Object, exists bool) bool {
+	if r.Header.Get("If-None-Match") == "*" && exists {
+		return false
+	}
+	if m := r.Header.Get("If-Match"); m != "" && (!exists || m != obj.etag) {
+		return false
+	}
+	return true
+}
+
+func writeFakeS3Error(w http.ResponseWriter, r *http.Request, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	// responses to HEAD requests have no body
+	if r.Method != http.MethodHead {
+		fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+	}
+}
+
+// Parses a header of the form "bytes=start-end".
+func parseRange(header string, size int) (int, int, bool) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || size == 0 {
+		return 0, 0, false
+	}
+	startStr, endStr, _ := strings.Cut(spec, "-")
+	start, err := strconv.Atoi(startStr)
+	if err != nil || start >= size {
+		return 0, 0, false
+	}
+	end, err := strconv.Atoi(endStr)
+	if err != nil || end >= size {
+		end = size - 1
+	}
+	return start, end, true
+}
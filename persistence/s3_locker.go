@@ -0,0 +1,148 @@
+package persistence
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+type s3Locker struct {
+	client     *s3.S3
+	bucketName string
+	key        string
+	ttl        time.Duration
+	owner      string
+}
+
+// The content of the lock object.
+type s3Lease struct {
+	Owner     string    `json:"owner"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Returns a lock held as an object on S3, which is created only if it does not exist by a conditional PUT.
+// The lock expires after ttl so that a crashed owner does not hold it forever,
+// so ttl must be longer than the work done while holding the lock.
+func NewS3Locker(
+	region string,
+	bucketName string, key string,
+	ttl time.Duration,
+	optFns ...func(*s3Conf),
+) (Locker, error) {
+	sess, err := newS3Session(region, optFns...)
+	if err != nil {
+		return nil, fmt.Errorf("create s3 locker: %w", err)
+	}
+	owner := make([]byte, 16)
+	if _, err := rand.Read(owner); err != nil {
+		return nil, fmt.Errorf("generate owner id: %w", err)
+	}
+
+	return &s3Locker{
+		client:     s3.New(sess),
+		bucketName: bucketName,
+		key:        key,
+		ttl:        ttl,
+		owner:      hex.EncodeToString(owner),
+	}, nil
+}
+
+func (l *s3Locker) TryLock(ctx context.Context) (func() error, error) {
+	etag, err := l.putLease(ctx, "If-None-Match", "*")
+	if isPreconditionFailed(err) {
+		// take over the lease if it has expired
+		etag, err = l.takeOverExpiredLease(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	unlock := func() error {
+		// the object is deleted only if the lease is not taken over after expiration
+		_, err := l.client.DeleteObjectWithContext(context.Background(), &s3.DeleteObjectInput{Bucket: &l.bucketName, Key: &l.key}, withHeader("If-Match", etag))
+		if isPreconditionFailed(err) {
+			return fmt.Errorf("lease has been taken over by another owner")
+		}
+		if err != nil {
+			return fmt.Errorf("delete lock object: %w", err)
+		}
+		return nil
+	}
+	return unlock, nil
+}
+
+func (l *s3Locker) takeOverExpiredLease(ctx context.Context) (string, error) {
+	obj, err := l.client.GetObjectWithContext(ctx, &s3.GetObjectInput{Bucket: &l.bucketName, Key: &l.key})
+	if err != nil {
+		var awsErr awserr.Error
+		if errors.As(err, &awsErr) && awsErr.Code() == s3.ErrCodeNoSuchKey {
+			// released just now; acquire it on the next attempt
+			return "", ErrLocked
+		}
+		return "", fmt.Errorf("get lock object: %w", err)
+	}
+	defer obj.Body.Close()
+	body, err := io.ReadAll(obj.Body)
+	if err != nil {
+		return "", fmt.Errorf("read lock object: %w", err)
+	}
+	lease := s3Lease{}
+	if err := json.Unmarshal(body, &lease); err != nil {
+		return "", fmt.Errorf("parse lock object: %w", err)
+	}
+	if time.Now().Before(lease.ExpiresAt) {
+		return "", ErrLocked
+	}
+
+	etag, err := l.putLease(ctx, "If-Match", aws.StringValue(obj.ETag))
+	if isPreconditionFailed(err) {
+		// another owner has taken it over first
+		return "", ErrLocked
+	}
+	return etag, err
+}
+
+// Writes a new lease conditionally and returns its ETag.
+func (l *s3Locker) putLease(ctx context.Context, conditionHeader, conditionValue string) (string, error) {
+	body, err := json.Marshal(s3Lease{Owner: l.owner, ExpiresAt: time.Now().Add(l.ttl)})
+	if err != nil {
+		return "", fmt.Errorf("marshal lease: %w", err)
+	}
+	out, err := l.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket: &l.bucketName,
+		Key:    &l.key,
+		Body:   bytes.NewReader(body),
+	}, withHeader(conditionHeader, conditionValue))
+	if err != nil {
+		if isPreconditionFailed(err) {
+			return "", err
+		}
+		return "", fmt.Errorf("put lock object: %w", err)
+	}
+	return aws.StringValue(out.ETag), nil
+}
+
+// Adds a header which the SDK does not support as a field, such as conditions of PutObject.
+func withHeader(name, value string) request.Option {
+	return func(r *request.Request) {
+		r.HTTPRequest.Header.Set(name, value)
+	}
+}
+
+// Reports whether a conditional request failed because the object exists or has been modified.
+// Concurrent conditional writes to the same key may also fail with 409 Conflict.
+func isPreconditionFailed(err error) bool {
+	return hasStatusCode(err, http.StatusPreconditionFailed) || hasStatusCode(err, http.StatusConflict)
+}
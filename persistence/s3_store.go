@@ -3,16 +3,56 @@ package persistence
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 )
 
+type s3Conf struct {
+	endpoint    string
+	pathStyle   bool
+	credentials *credentials.Credentials
+	profile     string
+}
+
+// Sets the endpoint URL of an S3-compatible service such as MinIO, e.g. http://localhost:9000.
+func WithS3Endpoint(endpoint string) func(c *s3Conf) {
+	return func(c *s3Conf) {
+		c.endpoint = endpoint
+	}
+}
+
+// Addresses buckets by the path instead of the host name, which most S3-compatible services require.
+func WithS3PathStyle(pathStyle bool) func(c *s3Conf) {
+	return func(c *s3Conf) {
+		c.pathStyle = pathStyle
+	}
+}
+
+// Uses the static credentials instead of the ones given by the environment.
+// The session token can be empty.
+func WithS3StaticCredentials(accessKeyID, secretAccessKey, sessionToken string) func(c *s3Conf) {
+	return func(c *s3Conf) {
+		c.credentials = credentials.NewStaticCredentials(accessKeyID, secretAccessKey, sessionToken)
+	}
+}
+
+// Uses the profile in the shared config and credentials files, e.g. ~/.aws/credentials.
+func WithS3Profile(profile string) func(c *s3Conf) {
+	return func(c *s3Conf) {
+		c.profile = profile
+	}
+}
+
 type s3Store struct {
 	sess       *session.Session
 	bucketName string
@@ -22,8 +62,9 @@ type s3Store struct {
 func NewS3Store(
 	region string,
 	bucketName string, key string,
+	optFns ...func(*s3Conf),
 ) (PersistentStore, error) {
-	sess, err := session.NewSession(&aws.Config{Region: &region})
+	sess, err := newS3Session(region, optFns...)
 	if err != nil {
 		return nil, fmt.Errorf("create s3 store: %w", err)
 	}
@@ -35,6 +76,47 @@ func NewS3Store(
 	}, nil
 }
 
+// Returns a function giving stores of keys in the bucket, which share a session.
+func NewS3StoreProvider(region string, bucketName string, optFns ...func(*s3Conf)) (func(key string) PersistentStore, error) {
+	sess, err := newS3Session(region, optFns...)
+	if err != nil {
+		return nil, fmt.Errorf("create s3 store: %w", err)
+	}
+
+	return func(key string) PersistentStore {
+		return &s3Store{
+			sess:       sess,
+			bucketName: bucketName,
+			key:        key,
+		}
+	}, nil
+}
+
+// Creates a session in the region. If the region is empty, it is given by the environment, e.g. AWS_REGION.
+func newS3Session(region string, optFns ...func(*s3Conf)) (*session.Session, error) {
+	c := &s3Conf{}
+	for _, f := range optFns {
+		f(c)
+	}
+
+	conf := &aws.Config{
+		S3ForcePathStyle: aws.Bool(c.pathStyle),
+		Credentials:      c.credentials,
+	}
+	if region != "" {
+		conf.Region = &region
+	}
+	if c.endpoint != "" {
+		conf.Endpoint = &c.endpoint
+	}
+	return session.NewSessionWithOptions(session.Options{
+		Config:  *conf,
+		Profile: c.profile,
+		// profiles may be defined only in ~/.aws/config
+		SharedConfigState: session.SharedConfigEnable,
+	})
+}
+
 func (s *s3Store) Load(ctx context.Context) ([]byte, error) {
 	d := s3manager.NewDownloader(s.sess)
 	buf := aws.NewWriteAtBuffer([]byte{})
@@ -45,19 +127,56 @@ func (s *s3Store) Load(ctx context.Context) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-func (s *s3Store) ModTime(ctx context.Context) (time.Time, bool, error) {
+func (s *s3Store) Open(ctx context.Context) (io.ReadCloser, error) {
 	client := s3.New(s.sess)
 	obj, err := client.GetObjectWithContext(ctx, &s3.GetObjectInput{Bucket: &s.bucketName, Key: &s.key})
 	if err != nil {
-		if awsErr, ok := err.(awserr.Error); ok {
-			switch awsErr.Code() {
-			case s3.ErrCodeNoSuchKey:
-				return time.Time{}, false, nil
-			}
+		return nil, fmt.Errorf("get object: %w", err)
+	}
+	return obj.Body, nil
+}
+
+func (s *s3Store) OpenIfModified(ctx context.Context, etag string) (io.ReadCloser, string, bool, error) {
+	client := s3.New(s.sess)
+	input := &s3.GetObjectInput{Bucket: &s.bucketName, Key: &s.key}
+	if etag != "" {
+		input.IfNoneMatch = &etag
+	}
+	obj, err := client.GetObjectWithContext(ctx, input)
+	if err != nil {
+		if hasStatusCode(err, http.StatusNotModified) {
+			return nil, etag, false, nil
 		}
-		return time.Time{}, true, fmt.Errorf("get object: %w", err)
+		return nil, "", false, fmt.Errorf("get object: %w", err)
 	}
-	return *obj.LastModified, true, nil
+	return obj.Body, aws.StringValue(obj.ETag), true, nil
+}
+
+func (s *s3Store) ModTime(ctx context.Context) (time.Time, bool, error) {
+	client := s3.New(s.sess)
+	obj, err := client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{Bucket: &s.bucketName, Key: &s.key})
+	if err != nil {
+		// responses to HEAD requests have no body to tell the error code
+		if hasStatusCode(err, http.StatusNotFound) {
+			return time.Time{}, false, nil
+		}
+		return time.Time{}, true, fmt.Errorf("head object: %w", err)
+	}
+	return aws.TimeValue(obj.LastModified), true, nil
+}
+
+// Reports whether the request failed with the HTTP status code.
+func hasStatusCode(err error, statusCode int) bool {
+	var reqErr awserr.RequestFailure
+	return errors.As(err, &reqErr) && reqErr.StatusCode() == statusCode
+}
+
+func (s *s3Store) Delete(ctx context.Context) error {
+	client := s3.New(s.sess)
+	if _, err := client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{Bucket: &s.bucketName, Key: &s.key}); err != nil {
+		return fmt.Errorf("delete object: %w", err)
+	}
+	return nil
 }
 
 func (s *s3Store) Save(ctx context.Context, data []byte) error {
@@ -69,3 +188,44 @@ func (s *s3Store) Save(ctx context.Context, data []byte) error {
 	}
 	return nil
 }
+
+// Returns a writer uploading data while it is written. Data larger than a part is uploaded in multiple parts,
+// and the object is replaced only after the writer is closed.
+func (s *s3Store) Create(ctx context.Context) (io.WriteCloser, error) {
+	pr, pw := io.Pipe()
+	w := &s3Writer{pw: pw, done: make(chan error, 1)}
+	u := s3manager.NewUploader(s.sess)
+	go func() {
+		_, err := u.UploadWithContext(ctx, &s3manager.UploadInput{Bucket: &s.bucketName, Key: &s.key, Body: pr})
+		// unblocks writes if the upload fails
+		pr.CloseWithError(err)
+		w.done <- err
+	}()
+	return w, nil
+}
+
+var errUploadAborted = errors.New("upload aborted")
+
+type s3Writer struct {
+	pw   *io.PipeWriter
+	done chan error
+}
+
+func (w *s3Writer) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+func (w *s3Writer) Close() error {
+	w.pw.Close()
+	if err := <-w.done; err != nil {
+		return fmt.Errorf("upload object: %w", err)
+	}
+	return nil
+}
+
+// Fails the upload, so that the object is not replaced and uploaded parts are deleted.
+func (w *s3Writer) Abort() error {
+	w.pw.CloseWithError(errUploadAborted)
+	<-w.done
+	return nil
+}
@@ -0,0 +1,314 @@
+package persistence_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/paralleltree/markov-bot-go/persistence"
+)
+
+func TestS3Store_SaveAndLoad(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	_, endpoint := newFakeS3(t)
+	store := newFakeS3Store(t, endpoint, "bot/model")
+
+	// act
+	errSave := store.Save(ctx, []byte("data"))
+	got, errLoad := store.Load(ctx)
+
+	// assert
+	if errSave != nil || errLoad != nil {
+		t.Fatalf("unexpected errors: %v, %v", errSave, errLoad)
+	}
+	if string(got) != "data" {
+		t.Fatalf("unexpected data: %q", got)
+	}
+}
+
+func TestS3Store_ModTime(t *testing.T) {
+	cases := []struct {
+		name      string
+		saved     bool
+		wantExist bool
+	}{
+		{name: "not found"},
+		{name: "saved", saved: true, wantExist: true},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			ctx := context.Background()
+			_, endpoint := newFakeS3(t)
+			store := newFakeS3Store(t, endpoint, "bot/model")
+			if tt.saved {
+				if err := store.Save(ctx, []byte("data")); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			// act
+			modTime, exists, err := store.ModTime(ctx)
+
+			// assert
+			if err != nil {
+				t.Fatalf("ModTime() should not return error, but got: %v", err)
+			}
+			if tt.wantExist != exists {
+				t.Fatalf("want existence %v, but got %v", tt.wantExist, exists)
+			}
+			// Last-Modified is precise to seconds
+			if exists && time.Since(modTime) > time.Minute {
+				t.Fatalf("unexpected modtime: %v", modTime)
+			}
+		})
+	}
+}
+
+func TestS3Store_ModTime_DoesNotDownloadObject(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	fake, endpoint := newFakeS3(t)
+	store := newFakeS3Store(t, endpoint, "bot/model")
+	if err := store.Save(ctx, []byte("data")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// act
+	if _, _, err := store.ModTime(ctx); err != nil {
+		t.Fatalf("ModTime() should not return error, but got: %v", err)
+	}
+
+	// assert
+	want := []string{"PUT bucket/bot/model", "HEAD bucket/bot/model"}
+	if !reflect.DeepEqual(want, fake.requestLog()) {
+		t.Fatalf("unexpected requests: want %v, but got %v", want, fake.requestLog())
+	}
+}
+
+// Reads and closes the stream.
+func readStream(t *testing.T, r io.ReadCloser) string {
+	t.Helper()
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return string(data)
+}
+
+func TestS3Store_OpenIfModified_SkipsUnmodifiedObject(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	_, endpoint := newFakeS3(t)
+	store := newFakeS3Store(t, endpoint, "bot/model")
+	if err := store.Save(ctx, []byte("v1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	loader := store.(persistence.ConditionalLoader)
+
+	// act
+	r, etag, firstModified, err := loader.OpenIfModified(ctx, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	first := readStream(t, r)
+	_, _, unchangedModified, err := loader.OpenIfModified(ctx, etag)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := store.Save(ctx, []byte("v2")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r, _, secondModified, err := loader.OpenIfModified(ctx, etag)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second := readStream(t, r)
+
+	// assert
+	if !firstModified || first != "v1" || etag == "" {
+		t.Fatalf("first load should return data with etag: %q, %q, %v", first, etag, firstModified)
+	}
+	if unchangedModified {
+		t.Fatalf("unchanged object should not be loaded")
+	}
+	if !secondModified || second != "v2" {
+		t.Fatalf("modified object should be loaded: %q, %v", second, secondModified)
+	}
+}
+
+func TestS3Store_Load_WhenNotFound_ReturnsError(t *testing.T) {
+	// arrange
+	_, endpoint := newFakeS3(t)
+	store := newFakeS3Store(t, endpoint, "bot/model")
+
+	// act
+	_, err := store.Load(context.Background())
+
+	// assert
+	if err == nil {
+		t.Fatalf("Load() should return error")
+	}
+}
+
+func TestS3Store_Delete_RemovesObject(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	_, endpoint := newFakeS3(t)
+	store := newFakeS3Store(t, endpoint, "bot/model")
+	if err := store.Save(ctx, []byte("data")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// act
+	err := store.(persistence.Deleter).Delete(ctx)
+
+	// assert
+	if err != nil {
+		t.Fatalf("Delete() should not return error, but got: %v", err)
+	}
+	if _, exists, err := store.ModTime(ctx); err != nil || exists {
+		t.Fatalf("object should be deleted: exists %v, err %v", exists, err)
+	}
+}
+
+func TestOpen_WithS3EndpointParameters_UsesEndpoint(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	t.Setenv("AWS_ACCESS_KEY_ID", "access-key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret-key")
+	fake, endpoint := newFakeS3(t)
+	store, err := persistence.Open("s3+gzip://bucket/bot/model?region=us-east-1&path_style=true&endpoint=" + endpoint)
+	if err != nil {
+		t.Fatalf("Open() should not return error, but got: %v", err)
+	}
+
+	// act
+	err = store.Save(ctx, []byte("data"))
+
+	// assert
+	if err != nil {
+		t.Fatalf("Save() should not return error, but got: %v", err)
+	}
+	if !fake.has("bucket/bot/model") {
+		t.Fatalf("object should be saved on the endpoint, but got requests: %v", fake.requests)
+	}
+}
+
+func TestOpen_WithS3Credentials_SignsRequestsWithThem(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	fake, endpoint := newFakeS3(t)
+	store, err := persistence.Open("s3://minio-key:minio-secret@bucket/model?region=us-east-1&path_style=true&endpoint=" + endpoint)
+	if err != nil {
+		t.Fatalf("Open() should not return error, but got: %v", err)
+	}
+
+	// act
+	err = store.Save(ctx, []byte("data"))
+
+	// assert
+	if err != nil {
+		t.Fatalf("Save() should not return error, but got: %v", err)
+	}
+	if !fake.has("bucket/model") {
+		t.Fatalf("Save() should put the object")
+	}
+	if want, got := []string{"minio-key"}, fake.signedAccessKeyIDs(); !reflect.DeepEqual(want, got) {
+		t.Errorf("unexpected access key ids: want %v, but got %v", want, got)
+	}
+}
+
+func TestOpenVersioned_WithS3EndpointParameters_SavesVersionsUnderPrefix(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	t.Setenv("AWS_ACCESS_KEY_ID", "access-key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret-key")
+	fake, endpoint := newFakeS3(t)
+	store, err := persistence.OpenVersioned("s3://bucket/bot/models/?region=us-east-1&path_style=true&endpoint=" + endpoint)
+	if err != nil {
+		t.Fatalf("OpenVersioned() should not return error, but got: %v", err)
+	}
+
+	// act
+	err = store.Save(ctx, []byte("data"))
+
+	// assert
+	if err != nil {
+		t.Fatalf("Save() should not return error, but got: %v", err)
+	}
+	m, err := store.Manifest(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !fake.has("bucket/bot/models/manifest.json") || !fake.has("bucket/bot/models/"+m.Active+".gz") {
+		t.Fatalf("versions should be saved under the prefix, but got requests: %v", fake.requestLog())
+	}
+	got, err := store.Load(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(got) != "data" {
+		t.Fatalf("unexpected data: %q", got)
+	}
+}
+
+func TestS3Locker_TryLock_ExcludesOtherOwnersUntilReleased(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	_, endpoint := newFakeS3(t)
+	owner := newFakeS3Locker(t, endpoint, "bot/lock", time.Minute)
+	other := newFakeS3Locker(t, endpoint, "bot/lock", time.Minute)
+	unlock, err := owner.TryLock(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// act
+	_, errWhileLocked := other.TryLock(ctx)
+	if err := unlock(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, errAfterUnlock := other.TryLock(ctx)
+
+	// assert
+	if !errors.Is(errWhileLocked, persistence.ErrLocked) {
+		t.Fatalf("want ErrLocked while locked, but got: %v", errWhileLocked)
+	}
+	if errAfterUnlock != nil {
+		t.Fatalf("lock should be acquired after unlocked, but got: %v", errAfterUnlock)
+	}
+}
+
+func TestS3Locker_TryLock_TakesOverExpiredLease(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	_, endpoint := newFakeS3(t)
+	// the lease expires as soon as it is acquired
+	crashed := newFakeS3Locker(t, endpoint, "bot/lock", -time.Second)
+	other := newFakeS3Locker(t, endpoint, "bot/lock", time.Minute)
+	unlockCrashed, err := crashed.TryLock(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// act
+	unlock, err := other.TryLock(ctx)
+
+	// assert
+	if err != nil {
+		t.Fatalf("expired lease should be taken over, but got: %v", err)
+	}
+	if err := unlockCrashed(); err == nil {
+		t.Fatalf("unlocking a lease taken over should return error")
+	}
+	if err := unlock(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package persistence
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+)
+
+// Opens the data stream of the store.
+// If the store is not a StreamStore, the whole data is loaded and read from memory.
+func OpenStream(ctx context.Context, store PersistentStore) (io.ReadCloser, error) {
+	if s, ok := store.(StreamStore); ok {
+		return s.Open(ctx)
+	}
+	data, err := store.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Saves data written by write to the store. The data is discarded if write returns an error.
+// If the store is not a StreamStore, the data is buffered in memory and saved at once.
+func SaveStream(ctx context.Context, store PersistentStore, write func(w io.Writer) error) error {
+	w, err := createStream(ctx, store)
+	if err != nil {
+		return err
+	}
+	if err := write(w); err != nil {
+		abortStream(w)
+		return err
+	}
+	return w.Close()
+}
+
+func createStream(ctx context.Context, store PersistentStore) (io.WriteCloser, error) {
+	if s, ok := store.(StreamStore); ok {
+		return s.Create(ctx)
+	}
+	return &bufferedWriter{save: func(data []byte) error {
+		return store.Save(ctx, data)
+	}}, nil
+}
+
+// Aborts the writer if possible. Writers without Abort are left unclosed, since closing saves the data.
+func abortStream(w io.WriteCloser) {
+	if a, ok := w.(Aborter); ok {
+		a.Abort()
+	}
+}
+
+// Writes data given to the writer at once on closing.
+type bufferedWriter struct {
+	bytes.Buffer
+	save func(data []byte) error
+}
+
+func (w *bufferedWriter) Close() error {
+	return w.save(w.Bytes())
+}
+
+func (w *bufferedWriter) Abort() error {
+	w.Reset()
+	return nil
+}
+
+// Saves data written by the writer at once.
+func writeAll(w io.WriteCloser, data []byte) error {
+	if _, err := w.Write(data); err != nil {
+		abortStream(w)
+		return fmt.Errorf("write data: %w", err)
+	}
+	return w.Close()
+}
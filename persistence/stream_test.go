@@ -0,0 +1,96 @@
+package persistence_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/paralleltree/markov-bot-go/persistence"
+)
+
+func newStreamTestStores(t *testing.T) map[string]persistence.PersistentStore {
+	t.Helper()
+	_, endpoint := newFakeS3(t)
+	_, versionStore := newVersionStores()
+	return map[string]persistence.PersistentStore{
+		"file":       persistence.NewFileStore(filepath.Join(t.TempDir(), "model")),
+		"memory":     persistence.NewMemoryStore(),
+		"s3":         newFakeS3Store(t, endpoint, "bot/model"),
+		"gzip":       persistence.NewCompressedStore(persistence.NewMemoryStore()),
+		"zstd+file":  persistence.NewZstdCompressedStore(persistence.NewFileStore(filepath.Join(t.TempDir(), "model"))),
+		"gzip+s3":    persistence.NewCompressedStore(newFakeS3Store(t, endpoint, "bot/model.gz")),
+		"versioned":  persistence.NewVersionedStore(persistence.NewMemoryStore(), versionStore),
+		"not stream": struct{ persistence.PersistentStore }{persistence.NewMemoryStore()},
+	}
+}
+
+func TestSaveStream_SavesDataReadByOpenStream(t *testing.T) {
+	for name, store := range newStreamTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			// arrange
+			ctx := context.Background()
+			want := strings.Repeat("data ", 1000)
+
+			// act
+			err := persistence.SaveStream(ctx, store, func(w io.Writer) error {
+				_, err := io.WriteString(w, want)
+				return err
+			})
+
+			// assert
+			if err != nil {
+				t.Fatalf("SaveStream() should not return error, but got: %v", err)
+			}
+			r, err := persistence.OpenStream(ctx, store)
+			if err != nil {
+				t.Fatalf("OpenStream() should not return error, but got: %v", err)
+			}
+			if got := readStream(t, r); want != got {
+				t.Fatalf("unexpected data: want %d bytes, but got %q", len(want), got)
+			}
+			loaded, err := store.Load(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want != string(loaded) {
+				t.Fatalf("loaded data differs from the stream")
+			}
+		})
+	}
+}
+
+func TestSaveStream_WhenWriteFails_KeepsPreviousData(t *testing.T) {
+	for name, store := range newStreamTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			// arrange
+			ctx := context.Background()
+			if err := store.Save(ctx, []byte("previous")); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			writeErr := errors.New("write failed")
+
+			// act
+			err := persistence.SaveStream(ctx, store, func(w io.Writer) error {
+				if _, err := io.WriteString(w, "partial"); err != nil {
+					return err
+				}
+				return writeErr
+			})
+
+			// assert
+			if !errors.Is(err, writeErr) {
+				t.Fatalf("SaveStream() should return the write error, but got: %v", err)
+			}
+			got, err := store.Load(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != "previous" {
+				t.Fatalf("previous data should be kept, but got %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,340 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrPinned is returned when saving to a versioned store pinned to a version by rollback.
+var ErrPinned = errors.New("pinned to a version")
+
+// Version describes a saved version of a versioned store.
+type Version struct {
+	ID      string    `json:"id"`
+	SavedAt time.Time `json:"saved_at"`
+	// The size of the data given to the store, which is uncompressed even if the version store compresses it.
+	Size int `json:"size"`
+	// Describes the saved data. Given by the describer of the store, if any.
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+}
+
+// VersionManifest lists saved versions and points to the active one.
+type VersionManifest struct {
+	Active string `json:"active"`
+	// If true, saving fails until unpinned, so that a rolled back version is not replaced by a rebuild.
+	Pinned bool `json:"pinned,omitempty"`
+	// Versions in order of saving, from the oldest one.
+	Versions []Version `json:"versions"`
+}
+
+func (m *VersionManifest) find(id string) (Version, bool) {
+	for _, v := range m.Versions {
+		if v.ID == id {
+			return v, true
+		}
+	}
+	return Version{}, false
+}
+
+type versionedStoreConf struct {
+	keep          int
+	describe      func(r io.Reader) (json.RawMessage, error)
+	warningWriter io.Writer
+}
+
+// Sets the number of versions to keep. Older versions are deleted on saving.
+func WithKeepVersions(keep int) func(c *versionedStoreConf) {
+	return func(c *versionedStoreConf) {
+		if keep > 0 {
+			c.keep = keep
+		}
+	}
+}
+
+// Sets the function to describe each saved data, which is recorded in the manifest.
+// The function reads the data from the saved version, and can stop reading once it is described.
+func WithVersionDescriber(describe func(r io.Reader) (json.RawMessage, error)) func(c *versionedStoreConf) {
+	return func(c *versionedStoreConf) {
+		c.describe = describe
+	}
+}
+
+// Writes failures which do not fail saving, such as deleting expired versions. They are discarded by default.
+func WithWarningWriter(w io.Writer) func(c *versionedStoreConf) {
+	return func(c *versionedStoreConf) {
+		c.warningWriter = w
+	}
+}
+
+type versionedStore struct {
+	manifest     PersistentStore
+	versionStore func(id string) PersistentStore
+	conf         *versionedStoreConf
+}
+
+// Returns a store which saves each data as a new version, given by versionStore with the version ID,
+// and loads the active version recorded in the manifest store.
+// Old versions are deleted if their stores implement Deleter.
+func NewVersionedStore(manifest PersistentStore, versionStore func(id string) PersistentStore, optFns ...func(*versionedStoreConf)) *versionedStore {
+	conf := &versionedStoreConf{
+		keep:          5,
+		warningWriter: io.Discard,
+	}
+	for _, f := range optFns {
+		f(conf)
+	}
+	return &versionedStore{
+		manifest:     manifest,
+		versionStore: versionStore,
+		conf:         conf,
+	}
+}
+
+func (s *versionedStore) Load(ctx context.Context) ([]byte, error) {
+	m, err := s.Manifest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if m.Active == "" {
+		return nil, fmt.Errorf("no version is saved")
+	}
+	data, err := s.versionStore(m.Active).Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load version %s: %w", m.Active, err)
+	}
+	return data, nil
+}
+
+func (s *versionedStore) Open(ctx context.Context) (io.ReadCloser, error) {
+	m, err := s.Manifest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.openVersion(ctx, m.Active)
+}
+
+func (s *versionedStore) openVersion(ctx context.Context, id string) (io.ReadCloser, error) {
+	if id == "" {
+		return nil, fmt.Errorf("no version is saved")
+	}
+	r, err := OpenStream(ctx, s.versionStore(id))
+	if err != nil {
+		return nil, fmt.Errorf("open version %s: %w", id, err)
+	}
+	return r, nil
+}
+
+// Uses the ID of the active version as the ETag, since saved versions are never modified.
+func (s *versionedStore) OpenIfModified(ctx context.Context, etag string) (io.ReadCloser, string, bool, error) {
+	m, err := s.Manifest(ctx)
+	if err != nil {
+		return nil, "", false, err
+	}
+	if m.Active != "" && m.Active == etag {
+		return nil, etag, false, nil
+	}
+	r, err := s.openVersion(ctx, m.Active)
+	if err != nil {
+		return nil, "", false, err
+	}
+	return r, m.Active, true, nil
+}
+
+// Returns the time the active version was saved.
+func (s *versionedStore) ModTime(ctx context.Context) (time.Time, bool, error) {
+	m, err := s.Manifest(ctx)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	v, ok := m.find(m.Active)
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	return v.SavedAt, true, nil
+}
+
+// Saves data as a new active version, and deletes versions exceeding the number to keep.
+func (s *versionedStore) Save(ctx context.Context, data []byte) error {
+	w, err := s.Create(ctx)
+	if err != nil {
+		return err
+	}
+	return writeAll(w, data)
+}
+
+// Returns a writer to a new version, which becomes active when the writer is closed.
+// Fails if the store is pinned.
+func (s *versionedStore) Create(ctx context.Context) (io.WriteCloser, error) {
+	m, err := s.Manifest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if m.Pinned {
+		return nil, fmt.Errorf("save version: %w: active version is %s", ErrPinned, m.Active)
+	}
+
+	savedAt := time.Now().UTC()
+	v := Version{
+		ID:      savedAt.Format("20060102T150405.000000000Z"),
+		SavedAt: savedAt,
+	}
+	w, err := createStream(ctx, s.versionStore(v.ID))
+	if err != nil {
+		return nil, fmt.Errorf("save version %s: %w", v.ID, err)
+	}
+	return &versionWriter{WriteCloser: w, ctx: ctx, store: s, version: v}, nil
+}
+
+// Counts the written size before compression by the version store, and records the version in the manifest on closing.
+type versionWriter struct {
+	io.WriteCloser
+	ctx     context.Context
+	store   *versionedStore
+	version Version
+}
+
+func (w *versionWriter) Write(p []byte) (int, error) {
+	n, err := w.WriteCloser.Write(p)
+	w.version.Size += n
+	return n, err
+}
+
+func (w *versionWriter) Close() error {
+	if err := w.WriteCloser.Close(); err != nil {
+		return fmt.Errorf("save version %s: %w", w.version.ID, err)
+	}
+	return w.store.addVersion(w.ctx, w.version)
+}
+
+func (w *versionWriter) Abort() error {
+	if a, ok := w.WriteCloser.(Aborter); ok {
+		return a.Abort()
+	}
+	return nil
+}
+
+// Makes the saved version active, and deletes versions exceeding the number to keep.
+// The saved version is deleted if it cannot be recorded in the manifest, since nothing would refer to it.
+func (s *versionedStore) addVersion(ctx context.Context, v Version) error {
+	m, err := s.Manifest(ctx)
+	if err != nil {
+		return s.discardVersion(ctx, v.ID, err)
+	}
+	// the store may be pinned while the version is written
+	if m.Pinned {
+		return s.discardVersion(ctx, v.ID, fmt.Errorf("save version: %w: active version is %s", ErrPinned, m.Active))
+	}
+	if s.conf.describe != nil {
+		r, err := s.openVersion(ctx, v.ID)
+		if err != nil {
+			return s.discardVersion(ctx, v.ID, err)
+		}
+		v.Metadata, err = s.conf.describe(r)
+		r.Close()
+		if err != nil {
+			return s.discardVersion(ctx, v.ID, fmt.Errorf("describe version: %w", err))
+		}
+	}
+
+	m.Active = v.ID
+	m.Versions = append(m.Versions, v)
+	var expired []Version
+	if len(m.Versions) > s.conf.keep {
+		expired = m.Versions[:len(m.Versions)-s.conf.keep]
+		m.Versions = m.Versions[len(m.Versions)-s.conf.keep:]
+	}
+	if err := s.saveManifest(ctx, m); err != nil {
+		return err
+	}
+
+	// expired versions are no longer referenced, so failing to delete them leaves only garbage,
+	// which is reported as a warning instead of failing the save already committed
+	for _, e := range expired {
+		if d, ok := s.versionStore(e.ID).(Deleter); ok {
+			if err := d.Delete(ctx); err != nil {
+				fmt.Fprintf(s.conf.warningWriter, "delete version %s: %v\n", e.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Deletes the version which is not recorded in the manifest, and returns err joined with the failure of deleting it.
+func (s *versionedStore) discardVersion(ctx context.Context, id string, err error) error {
+	d, ok := s.versionStore(id).(Deleter)
+	if !ok {
+		return err
+	}
+	if deleteErr := d.Delete(ctx); deleteErr != nil {
+		return errors.Join(err, fmt.Errorf("delete version %s: %w", id, deleteErr))
+	}
+	return err
+}
+
+// Returns the manifest, which is empty if nothing is saved.
+func (s *versionedStore) Manifest(ctx context.Context) (*VersionManifest, error) {
+	m := &VersionManifest{}
+	_, ok, err := s.manifest.ModTime(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get manifest modtime: %w", err)
+	}
+	if !ok {
+		return m, nil
+	}
+	data, err := s.manifest.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("parse manifest: %w", err)
+	}
+	return m, nil
+}
+
+// Makes the version active and pins it, so that saving fails until Unpin is called.
+func (s *versionedStore) Rollback(ctx context.Context, id string) error {
+	m, err := s.Manifest(ctx)
+	if err != nil {
+		return err
+	}
+	if _, ok := m.find(id); !ok {
+		return fmt.Errorf("version %s is not found", id)
+	}
+	m.Active = id
+	m.Pinned = true
+	return s.saveManifest(ctx, m)
+}
+
+// Reports whether the store is pinned by Rollback.
+func (s *versionedStore) Pinned(ctx context.Context) (bool, error) {
+	m, err := s.Manifest(ctx)
+	if err != nil {
+		return false, err
+	}
+	return m.Pinned, nil
+}
+
+// Allows saving new versions again after Rollback.
+func (s *versionedStore) Unpin(ctx context.Context) error {
+	m, err := s.Manifest(ctx)
+	if err != nil {
+		return err
+	}
+	m.Pinned = false
+	return s.saveManifest(ctx, m)
+}
+
+func (s *versionedStore) saveManifest(ctx context.Context, m *VersionManifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal manifest: %w", err)
+	}
+	if err := s.manifest.Save(ctx, data); err != nil {
+		return fmt.Errorf("save manifest: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package persistence_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paralleltree/markov-bot-go/persistence"
+)
+
+// Returns memory stores of versions by their IDs to inspect them.
+func newVersionStores() (map[string]persistence.PersistentStore, func(id string) persistence.PersistentStore) {
+	stores := map[string]persistence.PersistentStore{}
+	return stores, func(id string) persistence.PersistentStore {
+		if _, ok := stores[id]; !ok {
+			stores[id] = persistence.NewMemoryStore()
+		}
+		return stores[id]
+	}
+}
+
+func TestVersionedStore_Save_KeepsLatestVersions(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	stores, versionStore := newVersionStores()
+	describe := func(r io.Reader) (json.RawMessage, error) {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(string(data))
+	}
+	store := persistence.NewVersionedStore(persistence.NewMemoryStore(), versionStore, persistence.WithKeepVersions(2), persistence.WithVersionDescriber(describe))
+
+	// act
+	for _, data := range []string{"v1", "v2", "v3"} {
+		if err := store.Save(ctx, []byte(data)); err != nil {
+			t.Fatalf("Save() should not return error, but got: %v", err)
+		}
+	}
+
+	// assert
+	got, err := store.Load(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(got) != "v3" {
+		t.Fatalf("want the latest version, but got %q", got)
+	}
+	m, err := store.Manifest(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(m.Versions) != 2 || m.Active != m.Versions[1].ID {
+		t.Fatalf("unexpected manifest: %+v", m)
+	}
+	if string(m.Versions[0].Metadata) != `"v2"` {
+		t.Fatalf("unexpected metadata: %s", m.Versions[0].Metadata)
+	}
+	for id, s := range stores {
+		_, exists, err := s.ModTime(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, kept := findVersion(m, id); exists != kept {
+			t.Fatalf("version %s should be deleted unless kept: exists %v, kept %v", id, exists, kept)
+		}
+	}
+}
+
+func TestVersionedStore_Rollback_PinsVersionUntilUnpinned(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	_, versionStore := newVersionStores()
+	store := persistence.NewVersionedStore(persistence.NewMemoryStore(), versionStore)
+	for _, data := range []string{"good", "bad"} {
+		if err := store.Save(ctx, []byte(data)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	m, err := store.Manifest(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// act
+	errRollback := store.Rollback(ctx, m.Versions[0].ID)
+	loaded, errLoad := store.Load(ctx)
+	errPinned := store.Save(ctx, []byte("rebuilt"))
+	errUnpin := store.Unpin(ctx)
+	errUnpinned := store.Save(ctx, []byte("rebuilt"))
+
+	// assert
+	if errRollback != nil || errLoad != nil || errUnpin != nil || errUnpinned != nil {
+		t.Fatalf("unexpected errors: %v, %v, %v, %v", errRollback, errLoad, errUnpin, errUnpinned)
+	}
+	if string(loaded) != "good" {
+		t.Fatalf("want the rolled back version, but got %q", loaded)
+	}
+	if !errors.Is(errPinned, persistence.ErrPinned) {
+		t.Fatalf("want ErrPinned, but got: %v", errPinned)
+	}
+}
+
+func TestVersionedStore_Rollback_WithUnknownVersion_ReturnsError(t *testing.T) {
+	// arrange
+	_, versionStore := newVersionStores()
+	store := persistence.NewVersionedStore(persistence.NewMemoryStore(), versionStore)
+
+	// act
+	err := store.Rollback(context.Background(), "unknown")
+
+	// assert
+	if err == nil {
+		t.Fatalf("Rollback() should return error")
+	}
+}
+
+func findVersion(m *persistence.VersionManifest, id string) (persistence.Version, bool) {
+	for _, v := range m.Versions {
+		if v.ID == id {
+			return v, true
+		}
+	}
+	return persistence.Version{}, false
+}
+
+// fails deleting the data
+type undeletableStore struct {
+	persistence.PersistentStore
+}
+
+func (s undeletableStore) Delete(ctx context.Context) error {
+	return errors.New("delete failed")
+}
+
+func TestVersionedStore_Save_WhenDeletingExpiredVersionFails_Succeeds(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	stores, versionStore := newVersionStores()
+	warnings := &bytes.Buffer{}
+	store := persistence.NewVersionedStore(persistence.NewMemoryStore(), func(id string) persistence.PersistentStore {
+		return undeletableStore{versionStore(id)}
+	}, persistence.WithKeepVersions(1), persistence.WithWarningWriter(warnings))
+	if err := store.Save(ctx, []byte("v1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// act
+	err := store.Save(ctx, []byte("v2"))
+
+	// assert
+	if err != nil {
+		t.Fatalf("Save() should not return error, but got: %v", err)
+	}
+	got, err := store.Load(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(got) != "v2" {
+		t.Fatalf("want the saved version active, but got %q", got)
+	}
+	if len(stores) != 2 {
+		t.Fatalf("the expired version should be left, but got %d versions", len(stores))
+	}
+	if !strings.Contains(warnings.String(), "delete version") {
+		t.Errorf("the failure should be written as a warning, but got %q", warnings.String())
+	}
+}
+
+// Returns the IDs of versions whose data exists.
+func existingVersions(t *testing.T, stores map[string]persistence.PersistentStore) []string {
+	t.Helper()
+	ids := []string{}
+	for id, s := range stores {
+		_, exists, err := s.ModTime(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if exists {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+type rollbacker interface {
+	Rollback(ctx context.Context, id string) error
+}
+
+func TestVersionedStore_Save_WhenVersionCannotBeRecorded_DeletesIt(t *testing.T) {
+	cases := []struct {
+		name string
+		// called after writing the version and before closing it
+		beforeClose func(t *testing.T, store rollbacker, firstID string)
+		describe    func(r io.Reader) (json.RawMessage, error)
+		wantErr     error
+	}{
+		{
+			name: "pinned while writing",
+			beforeClose: func(t *testing.T, store rollbacker, firstID string) {
+				if err := store.Rollback(context.Background(), firstID); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			},
+			wantErr: persistence.ErrPinned,
+		},
+		{
+			name: "describing fails",
+			describe: func(r io.Reader) (json.RawMessage, error) {
+				data, err := io.ReadAll(r)
+				if err != nil || string(data) == "v2" {
+					return nil, errors.New("describe failed")
+				}
+				return json.Marshal(string(data))
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			ctx := context.Background()
+			stores, versionStore := newVersionStores()
+			store := persistence.NewVersionedStore(persistence.NewMemoryStore(), versionStore, persistence.WithVersionDescriber(tt.describe))
+			if err := store.Save(ctx, []byte("v1")); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			m, err := store.Manifest(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			w, err := store.Create(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := io.WriteString(w, "v2"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.beforeClose != nil {
+				tt.beforeClose(t, store, m.Active)
+			}
+
+			// act
+			err = w.Close()
+
+			// assert
+			if err == nil {
+				t.Fatalf("Close() should return error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("want %v, but got: %v", tt.wantErr, err)
+			}
+			if want, got := []string{m.Active}, existingVersions(t, stores); !reflect.DeepEqual(want, got) {
+				t.Errorf("only the recorded version should be left: want %v, but got %v", want, got)
+			}
+		})
+	}
+}
+
+// fails to get the modification time
+type brokenStore struct {
+	persistence.PersistentStore
+}
+
+func (s brokenStore) ModTime(ctx context.Context) (time.Time, bool, error) {
+	return time.Time{}, true, errors.New("broken")
+}
+
+func TestVersionedStore_ModTime_WhenReadingManifestFails_ReportsNoModel(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	_, versionStore := newVersionStores()
+	store := persistence.NewVersionedStore(brokenStore{persistence.NewMemoryStore()}, versionStore)
+
+	// act
+	_, ok, err := store.ModTime(ctx)
+
+	// assert
+	if err == nil {
+		t.Fatalf("ModTime() should return error")
+	}
+	if ok {
+		t.Errorf("ModTime() should not report the model exists on error")
+	}
+}